@@ -0,0 +1,301 @@
+package discover
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// discoveryVersion must be bumped whenever discovery starts producing
+// different results for the same files, so that stale cache entries are
+// ignored.
+const discoveryVersion = 16
+
+// version identifies the discovery code and the queries it runs, including
+// user queries, and the run-like calls it recognises besides the default
+// ones.
+func (cfg *config) version() string {
+	h := sha256.New()
+	h.Write(queryTRunStringLiteral)
+	h.Write(queryGinkgo)
+	for _, query := range cfg.queries {
+		h.Write(query.Source)
+	}
+	for _, runLike := range cfg.runLikes {
+		fmt.Fprintln(h, runLike)
+	}
+	return fmt.Sprintf("%d-%x", discoveryVersion, h.Sum(nil)[:8])
+}
+
+type CacheMode string
+
+const (
+	CacheOff       CacheMode = "off"
+	CacheRead      CacheMode = "read"
+	CacheReadWrite CacheMode = "readwrite"
+)
+
+func ParseCacheMode(mode string) (CacheMode, error) {
+	switch m := CacheMode(mode); m {
+	case CacheOff, CacheRead, CacheReadWrite:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown cache mode: %s", mode)
+}
+
+// DefaultCacheDir returns the directory under $XDG_CACHE_HOME (or the
+// platform equivalent) used for the cache.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "golisttests"), nil
+}
+
+// resultCache stores discovery results of a directory together with the
+// stat and the content hash of every test file in it. An entry is reused as
+// long as every file is unchanged and the discovery version matches. Suite
+// methods inherited from other packages are not tracked, so changes to them
+// are noticed once a test file of the directory changes.
+type resultCache struct {
+	dir     string
+	mode    CacheMode
+	version string
+}
+
+func newResultCache(dir string, mode CacheMode, version string) *resultCache {
+	return &resultCache{dir: dir, mode: mode, version: version}
+}
+
+// cachedFile describes a file the way it was given (relative to the working
+// directory of that run) and by its absolute path.
+type cachedFile struct {
+	Path    string `json:"path"`
+	Abs     string `json:"abs"`
+	ModTime int64  `json:"mtime"`
+	Size    int64  `json:"size"`
+	Hash    string `json:"hash"`
+}
+
+type cacheEntry struct {
+	Version string       `json:"version"`
+	Files   []cachedFile `json:"files"`
+	Tests   []Test       `json:"tests"`
+}
+
+// parsePackageTestNames returns cached results for the files if they are
+// still valid and parses them with cfg otherwise, whose version the cache
+// must have. A nil cache parses every time. Results of files that could not
+// be fully analysed are not stored, so their errors are reported again.
+func (c *resultCache) parsePackageTestNames(ctx context.Context, cfg *config, filenames []string) ([]Test, error) {
+	if c == nil || c.mode == CacheOff {
+		return cfg.parsePackageTestNames(ctx, filenames)
+	}
+	filename := c.entryFilename(filenames)
+	entry, err := c.load(filename)
+	if err == nil && entry.Version == c.version {
+		if files, ok := revalidate(entry.Files, filenames); ok {
+			if c.mode == CacheReadWrite && !sameFiles(files, entry.Files) {
+				entry.Files = files
+				c.store(filename, entry)
+			}
+			return withPackage(entry.Tests, filenames), nil
+		}
+	}
+
+	tests, err := cfg.parsePackageTestNames(ctx, filenames)
+	if err != nil {
+		return tests, err
+	}
+	if c.mode == CacheReadWrite {
+		files, err := statFiles(filenames)
+		if err == nil {
+			c.store(filename, &cacheEntry{Version: c.version, Files: files, Tests: tests})
+		}
+	}
+	return tests, nil
+}
+
+// PruneCache removes the entries of opts.CacheDir that can never be used
+// again: entries of another version of the discovery or of its extensions,
+// entries that cannot be read and entries of files that do not exist
+// anymore. It returns the number of removed entries.
+func PruneCache(opts Options) (int, error) {
+	return newResultCache(opts.CacheDir, opts.CacheMode, newConfig(opts.Queries, opts.RunLikes).version()).prune()
+}
+
+func (c *resultCache) prune() (int, error) {
+	if c.dir == "" {
+		return 0, fmt.Errorf("cache directory is unknown")
+	}
+	matches, err := filepath.Glob(filepath.Join(c.dir, "*.json"))
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, filename := range matches {
+		entry, err := c.load(filename)
+		if err == nil && entry.Version == c.version && filesExist(entry.Files) {
+			continue
+		}
+		if err := os.Remove(filename); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+func (c *resultCache) entryFilename(filenames []string) string {
+	h := sha256.New()
+	for _, filename := range filenames {
+		abs, err := filepath.Abs(filename)
+		if err != nil {
+			abs = filename
+		}
+		// Both forms take part: results mention files the way they were given.
+		fmt.Fprintf(h, "%s\x00%s\x00", abs, filename)
+	}
+	return filepath.Join(c.dir, hex.EncodeToString(h.Sum(nil))+".json")
+}
+
+func (c *resultCache) load(filename string) (*cacheEntry, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// store writes the entry atomically. The cache is best effort, so errors are
+// ignored.
+func (c *resultCache) store(filename string, entry *cacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return
+	}
+	f, err := ioutil.TempFile(c.dir, "tmp-")
+	if err != nil {
+		return
+	}
+	_, err = f.Write(data)
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filename)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+}
+
+func hashFile(filename string) (string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func statFiles(filenames []string) ([]cachedFile, error) {
+	var result []cachedFile
+	for _, filename := range filenames {
+		info, err := os.Stat(filename)
+		if err != nil {
+			return nil, err
+		}
+		hash, err := hashFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		abs, err := filepath.Abs(filename)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, cachedFile{
+			Path:    filename,
+			Abs:     abs,
+			ModTime: info.ModTime().UnixNano(),
+			Size:    info.Size(),
+			Hash:    hash,
+		})
+	}
+	return result, nil
+}
+
+// revalidate checks that cached files are the given files and that none of
+// them changed. Files whose stat changed are hashed, so a touched but
+// otherwise unchanged file keeps the entry valid; the updated stats are
+// returned.
+func revalidate(cached []cachedFile, filenames []string) ([]cachedFile, bool) {
+	if len(cached) != len(filenames) {
+		return nil, false
+	}
+	result := make([]cachedFile, len(cached))
+	for i, file := range cached {
+		if file.Path != filenames[i] {
+			return nil, false
+		}
+		info, err := os.Stat(file.Path)
+		if err != nil {
+			return nil, false
+		}
+		result[i] = file
+		if info.ModTime().UnixNano() == file.ModTime && info.Size() == file.Size {
+			continue
+		}
+		hash, err := hashFile(file.Path)
+		if err != nil || hash != file.Hash {
+			return nil, false
+		}
+		result[i].ModTime = info.ModTime().UnixNano()
+		result[i].Size = info.Size()
+	}
+	return result, true
+}
+
+func sameFiles(a, b []cachedFile) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func filesExist(files []cachedFile) bool {
+	for _, file := range files {
+		if _, err := os.Stat(file.Abs); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// withPackage refreshes the import path of cached tests, as it depends on
+// go.mod rather than on the test files.
+func withPackage(tests []Test, filenames []string) []Test {
+	pkg := importPath(filenames[0])
+	for i := range tests {
+		tests[i].Package = pkg
+	}
+	return tests
+}
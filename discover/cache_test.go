@@ -0,0 +1,61 @@
+package discover
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	dir := SpitDir(t, map[string]string{
+		"a_test.go": "package a\nfunc TestA(t *testing.T) {}\n",
+	})
+	filename := filepath.Join(dir, "a_test.go")
+	cache := newResultCache(filepath.Join(dir, "cache"), CacheReadWrite, "v1")
+	require.Equal(t, []string{"TestA"}, Names(cache.parsePackageTestNames(context.Background(), defaultConfig, []string{filename})))
+
+	// Same size and modification time: the entry is used without hashing.
+	info, err := os.Stat(filename)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filename, []byte("package a\nfunc TestB(t *testing.T) {}\n"), 0644))
+	require.NoError(t, os.Chtimes(filename, info.ModTime(), info.ModTime()))
+	require.Equal(t, []string{"TestA"}, Names(cache.parsePackageTestNames(context.Background(), defaultConfig, []string{filename})))
+
+	// Changed modification time and content: the file is parsed again.
+	later := info.ModTime().Add(time.Second)
+	require.NoError(t, os.Chtimes(filename, later, later))
+	require.Equal(t, []string{"TestB"}, Names(cache.parsePackageTestNames(context.Background(), defaultConfig, []string{filename})))
+
+	// Another version ignores the entry.
+	require.NoError(t, ioutil.WriteFile(filename, []byte("package a\nfunc TestC(t *testing.T) {}\n"), 0644))
+	require.NoError(t, os.Chtimes(filename, later, later))
+	require.Equal(t, []string{"TestB"}, Names(cache.parsePackageTestNames(context.Background(), defaultConfig, []string{filename})))
+	require.Equal(t, []string{"TestC"}, Names(newResultCache(cache.dir, CacheRead, "v2").parsePackageTestNames(context.Background(), defaultConfig, []string{filename})))
+
+	removed, err := newResultCache(cache.dir, CacheReadWrite, "v2").prune()
+	require.NoError(t, err)
+	require.Equal(t, 1, removed)
+}
+
+func TestCachePruneMissingFiles(t *testing.T) {
+	dir := SpitDir(t, map[string]string{
+		"a/a_test.go": "package a\nfunc TestA(t *testing.T) {}\n",
+		"b/b_test.go": "package b\nfunc TestB(t *testing.T) {}\n",
+	})
+	cache := newResultCache(filepath.Join(dir, "cache"), CacheReadWrite, "v1")
+	cache.parsePackageTestNames(context.Background(), defaultConfig, []string{filepath.Join(dir, "a", "a_test.go")})
+	cache.parsePackageTestNames(context.Background(), defaultConfig, []string{filepath.Join(dir, "b", "b_test.go")})
+	require.NoError(t, os.RemoveAll(filepath.Join(dir, "a")))
+
+	removed, err := cache.prune()
+	require.NoError(t, err)
+	require.Equal(t, 1, removed)
+	removed, err = cache.prune()
+	require.NoError(t, err)
+	require.Equal(t, 0, removed)
+}
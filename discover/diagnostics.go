@@ -0,0 +1,61 @@
+package discover
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Diagnostics are the errors of files that could not be fully analysed.
+// Tests found in the rest of the files, and in the rest of a file, are
+// still listed.
+type Diagnostics []error
+
+// Add appends err unless it is nil. Diagnostics are flattened.
+func (d *Diagnostics) Add(err error) {
+	switch e := err.(type) {
+	case nil:
+	case Diagnostics:
+		*d = append(*d, e...)
+	default:
+		*d = append(*d, err)
+	}
+}
+
+// Err returns the diagnostics as an error, or nil if there are none.
+func (d Diagnostics) Err() error {
+	if len(d) == 0 {
+		return nil
+	}
+	return d
+}
+
+func (d Diagnostics) Error() string {
+	lines := make([]string, len(d))
+	for i, err := range d {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Truncated marks a listing that was stopped before the whole tree was
+// analysed, because the context was done or a limit was reached. The tests
+// found until then are listed.
+type Truncated struct {
+	Reason error
+}
+
+func (t *Truncated) Error() string { return fmt.Sprintf("truncated: %v", t.Reason) }
+func (t *Truncated) Unwrap() error { return t.Reason }
+
+// IsTruncated reports whether err, or any of the Diagnostics it holds, is
+// Truncated.
+func IsTruncated(err error) bool {
+	var diags Diagnostics
+	diags.Add(err)
+	for _, err := range diags {
+		if _, ok := err.(*Truncated); ok {
+			return true
+		}
+	}
+	return false
+}
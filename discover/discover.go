@@ -0,0 +1,960 @@
+// Package discover finds Go tests, benchmarks, fuzz tests, examples, suite
+// methods, subtests and specs of the common test frameworks without
+// building the packages.
+package discover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var skipIdents = map[string]bool{
+	"new": true,
+}
+
+func isTestFilename(name string) bool {
+	return strings.HasSuffix(name, "_test.go")
+}
+
+// testingImportName returns the name the file refers to the testing package
+// by: the import alias, "." for a dot import or "testing" otherwise.
+func testingImportName(file *ast.File) string {
+	for _, spec := range file.Imports {
+		if spec.Path.Value != `"testing"` {
+			continue
+		}
+		if spec.Name != nil && spec.Name.Name != "_" {
+			return spec.Name.Name
+		}
+		return "testing"
+	}
+	return "testing"
+}
+
+// isTestingType reports whether expr is *<testingPkg>.<typeName>, where
+// testingPkg is the name the testing package is imported by.
+func isTestingType(expr ast.Expr, testingPkg string, typeName string) bool {
+	star, ok := expr.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	switch t := star.X.(type) {
+	case *ast.SelectorExpr:
+		pkg, ok := t.X.(*ast.Ident)
+		return ok && pkg.Name == testingPkg && t.Sel.Name == typeName
+	case *ast.Ident:
+		return testingPkg == "." && t.Name == typeName
+	}
+	return false
+}
+
+func isSingleArgumentTesting(fn *ast.FuncDecl, testingPkg string, typeName string) bool {
+	if len(fn.Type.Params.List) != 1 {
+		return false
+	}
+	return isTestingType(fn.Type.Params.List[0].Type, testingPkg, typeName)
+}
+
+// getReceiverTypeNoStar returns the name of the receiver type of the method
+// without the pointer and, for generic types, without the type parameters.
+func getReceiverTypeNoStar(fn *ast.FuncDecl) (string, error) {
+	expr := fn.Recv.List[0].Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	switch t := expr.(type) {
+	case *ast.IndexExpr:
+		expr = t.X
+	case *ast.IndexListExpr:
+		expr = t.X
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name, nil
+	}
+	return "", fmt.Errorf("unsupported receiver type %T of %s", fn.Recv.List[0].Type, fn.Name.Name)
+}
+
+func hasReceiver(fn *ast.FuncDecl) bool {
+	return fn.Recv != nil
+}
+
+func hasReceiverAndNoArguments(fn *ast.FuncDecl) bool {
+	if len(fn.Type.Params.List) != 0 {
+		return false
+	}
+	return hasReceiver(fn)
+}
+
+func isTestName(name string) bool {
+	return strings.HasPrefix(name, "Test")
+}
+
+func isBenchmarkName(name string) bool {
+	return strings.HasPrefix(name, "Benchmark")
+}
+
+func isFuzzName(name string) bool {
+	return strings.HasPrefix(name, "Fuzz")
+}
+
+func isExampleName(name string) bool {
+	return strings.HasPrefix(name, "Example")
+}
+
+// isSimpleTestOf reports whether fn is a test function of a file that
+// imports the testing package as testingPkg. isBenchmarkOf and isFuzzOf do
+// the same for benchmarks and fuzz tests.
+func isSimpleTestOf(fn *ast.FuncDecl, testingPkg string) bool {
+	return isTestName(fn.Name.Name) && !hasReceiver(fn) && isSingleArgumentTesting(fn, testingPkg, "T")
+}
+
+func isBenchmarkOf(fn *ast.FuncDecl, testingPkg string) bool {
+	return isBenchmarkName(fn.Name.Name) && !hasReceiver(fn) && isSingleArgumentTesting(fn, testingPkg, "B")
+}
+
+func isFuzzOf(fn *ast.FuncDecl, testingPkg string) bool {
+	return isFuzzName(fn.Name.Name) && !hasReceiver(fn) && isSingleArgumentTesting(fn, testingPkg, "F")
+}
+
+var outputComment = regexp.MustCompile(`(?i)^[[:space:]]*(unordered )?output:`)
+
+// isExample reports whether fn is an example that go test runs, i.e. one
+// whose body ends with an output comment.
+func isExample(fn *ast.FuncDecl, comments []*ast.CommentGroup) bool {
+	if !isExampleName(fn.Name.Name) || hasReceiver(fn) || fn.Body == nil {
+		return false
+	}
+	if len(fn.Type.Params.List) != 0 || (fn.Type.Results != nil && len(fn.Type.Results.List) != 0) {
+		return false
+	}
+	var last *ast.CommentGroup
+	for _, group := range comments {
+		if group.Pos() > fn.Body.Lbrace && group.End() < fn.Body.Rbrace {
+			last = group
+		}
+	}
+	return last != nil && outputComment.MatchString(last.Text())
+}
+
+func isPossibleSuiteTest(fn *ast.FuncDecl) bool {
+	return isTestName(fn.Name.Name) && hasReceiverAndNoArguments(fn)
+}
+
+func getFirstIdent(expr ast.Expr) (*ast.Ident, error) {
+	var result *ast.Ident
+	ast.Inspect(expr, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Ident); ok {
+			if _, ok := skipIdents[ident.Name]; ok {
+				return true
+			}
+			if result == nil {
+				result = ident
+			}
+		}
+		return true
+	})
+	if result == nil {
+		return nil, fmt.Errorf("cannot find first identifier")
+	}
+	return result, nil
+}
+
+// findSuiteRunTypes returns identifiers of the suite types the test passes
+// to suite.Run. Arguments no type can be found in are reported in the error,
+// the other suites are returned anyway.
+func findSuiteRunTypes(fn *ast.FuncDecl) ([]*ast.Ident, error) {
+	seen := make(map[string]bool)
+	result := make([]*ast.Ident, 0)
+	var diags Diagnostics
+	ast.Inspect(fn, func(node ast.Node) bool {
+		if call, ok := node.(*ast.CallExpr); ok {
+			if len(call.Args) != 2 {
+				return true
+			}
+			callName := fmt.Sprintf("%s", call.Fun)
+			if callName != "&{suite Run}" {
+				return true
+			}
+			ident, err := getFirstIdent(call.Args[1])
+			if err != nil {
+				diags.Add(fmt.Errorf("suite.Run in %s: %v", fn.Name.Name, err))
+				return true
+			}
+			//fmt.Printf("found call (maybe var), ident name=%v, ident at=%v\n", ident.Name, fset.Position(ident.Pos()))
+			if _, ok := seen[ident.Name]; !ok {
+				result = append(result, ident)
+				seen[ident.Name] = true
+			}
+		}
+		return true
+	})
+	return result, diags.Err()
+}
+
+type testTracker struct {
+	result                       []Test
+	seenTests                    map[string]bool
+	suiteTypesAndTestsWhoRanThem map[string]map[string]bool
+}
+
+func newTestTracker() *testTracker {
+	return &testTracker{
+		result:                       make([]Test, 0),
+		seenTests:                    make(map[string]bool, 0),
+		suiteTypesAndTestsWhoRanThem: make(map[string]map[string]bool, 0),
+	}
+}
+
+func (t *testTracker) addTest(test Test) {
+	if _, ok := t.seenTests[test.Name]; !ok {
+		t.result = append(t.result, test)
+		t.seenTests[test.Name] = true
+	}
+}
+
+func (t *testTracker) sortedTests() []Test {
+	sort.Slice(t.result, func(i, j int) bool {
+		return t.result[i].Name < t.result[j].Name
+	})
+	return t.result
+}
+
+func (t *testTracker) suiteRanByTest(suiteTypeName string, testName string) {
+	if _, ok := t.suiteTypesAndTestsWhoRanThem[suiteTypeName]; !ok {
+		t.suiteTypesAndTestsWhoRanThem[suiteTypeName] = make(map[string]bool, 0)
+	}
+	t.suiteTypesAndTestsWhoRanThem[suiteTypeName][testName] = true
+}
+
+func (t *testTracker) whoRanSuiteType(suiteTypeName string) []string {
+	if _, ok := t.suiteTypesAndTestsWhoRanThem[suiteTypeName]; !ok {
+		return []string{}
+	}
+	var result []string
+	for testName, _ := range t.suiteTypesAndTestsWhoRanThem[suiteTypeName] {
+		result = append(result, testName)
+	}
+	return result
+}
+
+type typeResolver struct {
+	fset  *token.FileSet
+	files []*ast.File
+	info  *types.Info
+}
+
+// newTypeResolver type-checks the files. Once ctx is done, imports fail and
+// the types that depend on them stay unknown.
+func newTypeResolver(ctx context.Context, fset *token.FileSet, files ...*ast.File) *typeResolver {
+	return checkTypes(ctx, fset, importer.ForCompiler(fset, runtime.Compiler, nil), files)
+}
+
+// checkTypes type-checks the files with the given importer.
+func checkTypes(ctx context.Context, fset *token.FileSet, imp types.Importer, files []*ast.File) *typeResolver {
+	conf := types.Config{
+		Importer: &contextImporter{ctx, imp},
+		Error:    func(error) {},
+	}
+	info := &types.Info{
+		//Defs: make(map[*ast.Ident]types.Object),
+		Uses: make(map[*ast.Ident]types.Object),
+	}
+	if ctx.Err() == nil {
+		_, err := conf.Check("", fset, files, info)
+		if err != nil {
+			// it's best effort, so ignore errors, e.g. import errors
+		}
+	}
+
+	return &typeResolver{
+		fset:  fset,
+		files: files,
+		info:  info,
+	}
+}
+
+// withModuleSources type-checks the files again, importing packages of the
+// module from source, so that types declared in other packages of the
+// module are known even without compiled export data.
+func (this *typeResolver) withModuleSources(ctx context.Context, module *goModule) *typeResolver {
+	return checkTypes(ctx, this.fset, newModuleImporter(ctx, this.fset, module), this.files)
+}
+
+// contextImporter stops importing once ctx is done.
+type contextImporter struct {
+	ctx context.Context
+	imp types.Importer
+}
+
+func (c *contextImporter) Import(path string) (*types.Package, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.imp.Import(path)
+}
+
+// resolveType returns the type ident refers to, without a pointer, or nil if
+// it is unknown.
+func (this *typeResolver) resolveType(ident *ast.Ident) types.Type {
+	obj, ok := this.info.Uses[ident]
+	if !ok {
+		return nil
+	}
+	if p, ok := obj.Type().(*types.Pointer); ok {
+		return p.Elem()
+	}
+	return obj.Type()
+}
+
+// resolve returns the name of the type ident refers to, without a pointer.
+// Generic types, instantiated or not, are named without type arguments,
+// like the receivers of their methods.
+func (this *typeResolver) resolve(ident *ast.Ident) string {
+	for id, obj := range this.info.Uses {
+		if ident.Name == obj.Name() && ident.Pos() == id.Pos() {
+			t := obj.Type()
+			if p, ok := t.(*types.Pointer); ok {
+				t = p.Elem()
+			}
+			if named, ok := t.(*types.Named); ok && (named.TypeParams().Len() > 0 || named.TypeArgs().Len() > 0) {
+				obj := named.Origin().Obj()
+				if obj.Pkg() != nil && obj.Pkg().Path() != "" {
+					return obj.Pkg().Path() + "." + obj.Name()
+				}
+				return obj.Name()
+			}
+			return t.String()
+		}
+	}
+	return ident.Name // fallback
+	//return ""
+}
+
+type Kind string
+
+const (
+	KindSimple      Kind = "simple"
+	KindSuiteMethod Kind = "suite-method"
+	KindSubtest     Kind = "subtest"
+	KindTableCase   Kind = "table-case"
+
+	KindGinkgoContainer Kind = "ginkgo-container"
+	KindGinkgoSpec      Kind = "ginkgo-spec"
+
+	KindGocheckMethod Kind = "gocheck-method"
+)
+
+// TestType is the kind of function go test runs: a test, a benchmark,
+// a fuzz test or an example. Ginkgo specs and gocheck suite methods run
+// inside a test, but are selected differently.
+type TestType string
+
+const (
+	TypeTest    TestType = "test"
+	TypeBench   TestType = "bench"
+	TypeFuzz    TestType = "fuzz"
+	TypeExample TestType = "example"
+	TypeGinkgo  TestType = "ginkgo"
+	TypeGocheck TestType = "gocheck"
+)
+
+// TypeOf returns the type of the test with the given (possibly nested) name.
+func TypeOf(name string) TestType {
+	switch {
+	case isBenchmarkName(name):
+		return TypeBench
+	case isFuzzName(name):
+		return TypeFuzz
+	case isExampleName(name):
+		return TypeExample
+	}
+	return TypeTest
+}
+
+type Discoverer string
+
+const (
+	DiscovererGoAST      Discoverer = "go/ast"
+	DiscovererTreeSitter Discoverer = "tree-sitter"
+)
+
+// Test is a discovered test together with the place it was found at.
+// Parent is the name of the enclosing test for suite methods and subtests.
+type Test struct {
+	Name       string     `json:"name"`
+	Parent     string     `json:"parent,omitempty"`
+	Package    string     `json:"package"`
+	File       string     `json:"file"`
+	Line       int        `json:"line"`
+	Column     int        `json:"column"`
+	Type       TestType   `json:"type"`
+	Kind       Kind       `json:"kind"`
+	Discoverer Discoverer `json:"discoverer"`
+}
+
+// config extends discovery with user queries, run by tree-sitter, and with
+// run-like calls recognised besides the default ones.
+type config struct {
+	queries  []UserQuery
+	runLikes []RunLike
+}
+
+func newConfig(queries []UserQuery, runLikes []RunLike) *config {
+	return &config{
+		queries:  queries,
+		runLikes: append(append([]RunLike{}, defaultRunLikes...), runLikes...),
+	}
+}
+
+// defaultConfig discovers tests without extensions.
+var defaultConfig = newConfig(nil, nil)
+
+// parsePackageTestNames discovers tests in test files of a single directory.
+// The files are analysed together, so suites may be run in one file and
+// have their methods declared in another. Files that cannot be fully
+// analysed are reported in the error, tests found anyway are returned. Once
+// ctx is done, the tests found so far are returned with its error.
+func (cfg *config) parsePackageTestNames(ctx context.Context, filenames []string) ([]Test, error) {
+	result := []Test{}
+	result1 := []Test{}
+	result2 := []Test{}
+	var err1 error
+	var diags2 Diagnostics
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		result1, err1 = cfg.parsePackageGolangAST(ctx, filenames)
+		wg.Done()
+	}()
+	go func() {
+		for _, filename := range filenames {
+			if err := ctx.Err(); err != nil {
+				diags2.Add(err)
+				break
+			}
+			tests, err := cfg.scanTreeSitter(ctx, filename)
+			result2 = append(result2, tests...)
+			diags2.Add(err)
+		}
+		wg.Done()
+	}()
+	wg.Wait()
+	var diags Diagnostics
+	diags.Add(err1)
+	diags.Add(diags2.Err())
+	if len(filenames) == 0 {
+		return result, diags.Err()
+	}
+	// Inherited suite methods may be declared in another package, so the
+	// package is the one of the test files rather than of the test.
+	pkg := importPath(filenames[0])
+	// Both discoverers may find the same test, the go/ast record wins.
+	seen := map[string]bool{}
+	for _, test := range append(result1, result2...) {
+		if seen[test.Name] {
+			continue
+		}
+		seen[test.Name] = true
+		test.Package = pkg
+		result = append(result, test)
+	}
+	return result, diags.Err()
+}
+
+// parsePackageGolangAST parses the given files and scans every package
+// (a directory may hold both foo and foo_test) as a whole. Files that do
+// not parse are skipped and reported in the error.
+func (cfg *config) parsePackageGolangAST(ctx context.Context, filenames []string) ([]Test, error) {
+	fset := token.NewFileSet()
+	var names []string
+	var diags Diagnostics
+	packages := map[string][]*ast.File{}
+	for _, filename := range filenames {
+		if err := ctx.Err(); err != nil {
+			return []Test{}, err
+		}
+		// A file with syntax errors is still scanned for the declarations
+		// that parsed.
+		node, err := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+		diags.Add(err)
+		if node == nil || node.Name == nil {
+			continue
+		}
+		name := node.Name.Name
+		if _, ok := packages[name]; !ok {
+			names = append(names, name)
+		}
+		packages[name] = append(packages[name], node)
+	}
+	result := []Test{}
+	for _, name := range names {
+		tests, err := scanPackageGolangAST(ctx, fset, packages[name], cfg.runLikes)
+		result = append(result, tests...)
+		diags.Add(err)
+	}
+	return result, diags.Err()
+}
+
+// scanPackageGolangAST finds tests in the files of a package. Declarations
+// that cannot be analysed are reported in the error and skipped.
+func scanPackageGolangAST(ctx context.Context, fset *token.FileSet, files []*ast.File, runLikes []RunLike) ([]Test, error) {
+	newTest := func(at token.Pos, name string, parent string, kind Kind) Test {
+		pos := fset.Position(at)
+		return Test{
+			Name:       name,
+			Parent:     parent,
+			File:       pos.Filename,
+			Line:       pos.Line,
+			Column:     pos.Column,
+			Type:       TypeOf(name),
+			Kind:       kind,
+			Discoverer: DiscovererGoAST,
+		}
+	}
+	// Files are scanned twice, so the same problem is only reported once.
+	var diags Diagnostics
+	reported := map[string]bool{}
+	report := func(at token.Pos, err error) {
+		err = fmt.Errorf("%s: %v", fset.Position(at), err)
+		if !reported[err.Error()] {
+			reported[err.Error()] = true
+			diags.Add(err)
+		}
+	}
+	resolver := newTypeResolver(ctx, fset, files...)
+	tracker := newTestTracker()
+	var runners []suiteRunner
+
+	// gocheck suites are registered and run once per package, so they are
+	// collected before the methods are matched with them.
+	gocheckSuites := map[string]bool{}
+	var gocheckRunners []string
+	for _, node := range files {
+		checkPkg := gocheckImportName(node)
+		idents, err := findGocheckSuites(node, checkPkg)
+		if err != nil {
+			report(node.Pos(), err)
+		}
+		for _, ident := range idents {
+			gocheckSuites[resolver.resolve(ident)] = true
+		}
+		testingPkg := testingImportName(node)
+		for _, f := range node.Decls {
+			if fn, ok := f.(*ast.FuncDecl); ok && isSimpleTestOf(fn, testingPkg) && isGocheckRunner(fn, checkPkg) {
+				gocheckRunners = append(gocheckRunners, fn.Name.Name)
+			}
+		}
+	}
+
+	scan := func(node *ast.File) {
+		testingPkg := testingImportName(node)
+		checkPkg := gocheckImportName(node)
+		for _, f := range node.Decls {
+			if ctx.Err() != nil {
+				return
+			}
+			if fn, ok := f.(*ast.FuncDecl); ok {
+				testName := fn.Name.Name
+				if isSimpleTestOf(fn, testingPkg) {
+					tracker.addTest(newTest(fn.Pos(), testName, "", KindSimple))
+					idents, err := findSuiteRunTypes(fn)
+					if err != nil {
+						report(fn.Pos(), err)
+					}
+					for _, runnableSuiteTypeIdent := range idents {
+						typeName := resolver.resolve(runnableSuiteTypeIdent)
+						//fmt.Printf("resolve %v => %v\n", runnableSuiteTypeIdent.Name, typeName)
+						if typeName != "" {
+							tracker.suiteRanByTest(typeName, testName)
+						}
+						runners = append(runners, suiteRunner{runnableSuiteTypeIdent, testName})
+					}
+				}
+				if isBenchmarkOf(fn, testingPkg) || isFuzzOf(fn, testingPkg) || isExample(fn, node.Comments) {
+					tracker.addTest(newTest(fn.Pos(), testName, "", KindSimple))
+				}
+				if isSimpleTestOf(fn, testingPkg) || isBenchmarkOf(fn, testingPkg) {
+					for _, subtest := range findSubtests(fn, testName, runLikes) {
+						tracker.addTest(newTest(subtest.Pos, subtest.Name, subtest.Parent, subtest.Kind))
+					}
+				}
+				if !isGocheckTest(fn, checkPkg) && !isPossibleSuiteTest(fn) {
+					continue
+				}
+				receiverTypeName, err := getReceiverTypeNoStar(fn)
+				if err != nil {
+					report(fn.Pos(), err)
+					continue
+				}
+				if isGocheckTest(fn, checkPkg) && gocheckSuites[receiverTypeName] {
+					for _, runner := range gocheckRunners {
+						test := newTest(fn.Pos(), receiverTypeName+"."+testName, runner, KindGocheckMethod)
+						test.Type = TypeGocheck
+						tracker.addTest(test)
+					}
+				}
+				if isPossibleSuiteTest(fn) {
+					for _, testNameWhoRan := range tracker.whoRanSuiteType(receiverTypeName) {
+						methodTestName := testNameWhoRan + "/" + testName
+						tracker.addTest(newTest(fn.Pos(), methodTestName, testNameWhoRan, KindSuiteMethod))
+						for _, subtest := range findSuiteSubtests(fn, methodTestName, runLikes) {
+							tracker.addTest(newTest(subtest.Pos, subtest.Name, subtest.Parent, subtest.Kind))
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// Suites can be run after their methods are declared, so the second
+	// pass picks up methods seen before the runner.
+	for i := 0; i < 2; i++ {
+		for _, node := range files {
+			scan(node)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		diags.Add(err)
+		return tracker.sortedTests(), diags.Err()
+	}
+
+	// Suite types may embed other suites and inherit their test methods,
+	// possibly from other packages of the module.
+	if len(runners) > 0 && len(files) > 0 {
+		if needsModuleSources(resolver, runners) {
+			if module := findModule(filepath.Dir(fset.Position(files[0].Pos()).Filename)); module != nil {
+				resolver = resolver.withModuleSources(ctx, module)
+			}
+		}
+		for _, runner := range runners {
+			for _, method := range promotedSuiteMethods(resolver.resolveType(runner.ident)) {
+				name := runner.testName + "/" + method.Name()
+				test := newTest(method.Pos(), name, runner.testName, KindSuiteMethod)
+				test.File = relativeLike(test.File, fset.Position(files[0].Pos()).Filename)
+				tracker.addTest(test)
+			}
+		}
+	}
+	return tracker.sortedTests(), diags.Err()
+}
+
+// relativeLike returns filename relative to the working directory when like,
+// the name of a test file, is relative. Inherited suite methods are found
+// by absolute path, so every record of a listing has the same form.
+func relativeLike(filename string, like string) string {
+	if filepath.IsAbs(like) || !filepath.IsAbs(filename) {
+		return filename
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return filename
+	}
+	rel, err := filepath.Rel(wd, filename)
+	if err != nil {
+		return filename
+	}
+	return rel
+}
+
+// sortTests sorts tests by name and package.
+func sortTests(tests []Test) {
+	sort.SliceStable(tests, func(i, j int) bool {
+		if tests[i].Name != tests[j].Name {
+			return tests[i].Name < tests[j].Name
+		}
+		return tests[i].Package < tests[j].Package
+	})
+}
+
+// ParseTestTypes parses a comma-separated list of test types.
+func ParseTestTypes(list string) (map[TestType]bool, error) {
+	result := map[TestType]bool{}
+	for _, name := range strings.Split(list, ",") {
+		switch t := TestType(strings.TrimSpace(name)); t {
+		case TypeTest, TypeBench, TypeFuzz, TypeExample, TypeGinkgo, TypeGocheck:
+			result[t] = true
+		default:
+			return nil, fmt.Errorf("unknown test type: %s", name)
+		}
+	}
+	return result, nil
+}
+
+// testFilesInDir returns test files of a directory in lexical order,
+// including symlinks to regular files.
+func testFilesInDir(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for _, entry := range entries {
+		if !isTestFilename(entry.Name()) {
+			continue
+		}
+		filename := filepath.Join(dir, entry.Name())
+		// Symlinks are followed, like the go tool does.
+		if entry.Mode()&os.ModeSymlink != 0 {
+			if entry, err = os.Stat(filename); err != nil {
+				continue
+			}
+		}
+		if entry.Mode().IsRegular() {
+			result = append(result, filename)
+		}
+	}
+	return result, nil
+}
+
+// streamTestNames walks root and parses test files of every directory in a
+// pool of jobs workers, calling emit with every test as soon as its package
+// is parsed, in no particular order. Each test is emitted once, from a single
+// goroutine. An error of emit stops the listing and is returned. Files that
+// cannot be fully analysed are reported in Diagnostics; unless keepGoing is
+// set, the first of them stops the listing. The walk stops after maxFiles
+// paths, unless it is zero, and once ctx is done; the Diagnostics then
+// include Truncated.
+func (cfg *config) streamTestNames(ctx context.Context, root string, maxFiles int, jobs int, cache *resultCache, ignorer *pathIgnorer, keepGoing bool, emit func(Test) error) error {
+	if jobs < 1 {
+		jobs = 1
+	}
+	if ignorer == nil {
+		ignorer = newPathIgnorer(root, nil, nil)
+	}
+	type result struct {
+		tests []Test
+		err   error
+	}
+	packages := make(chan []string)
+	results := make(chan result)
+	var failed int32
+	var wg sync.WaitGroup
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for filenames := range packages {
+				if atomic.LoadInt32(&failed) != 0 {
+					continue
+				}
+				if err := ctx.Err(); err != nil {
+					results <- result{err: err}
+					continue
+				}
+				tests, err := cache.parsePackageTestNames(ctx, cfg, filenames)
+				results <- result{tests, err}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	walked := make(chan error, 1)
+	go func() {
+		defer close(packages)
+		numFiles := 0
+		walked <- filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			// A test file given as root is parsed on its own.
+			if path == root && !info.IsDir() {
+				if isTestFilename(path) {
+					packages <- []string{path}
+				}
+				return nil
+			}
+			if ignorer.isIgnored(path, info.IsDir()) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if numFiles++; maxFiles > 0 && numFiles > maxFiles {
+				return &Truncated{fmt.Errorf("number of files exceeded limit (%d)", maxFiles)}
+			}
+			if atomic.LoadInt32(&failed) != 0 {
+				return errStopped
+			}
+			if err := ctx.Err(); err != nil {
+				return &Truncated{err}
+			}
+			if !info.IsDir() {
+				return nil
+			}
+			filenames, err := testFilesInDir(path)
+			if err != nil {
+				return err
+			}
+			filenames = ignorer.filter(filenames)
+			if len(filenames) > 0 {
+				packages <- filenames
+			}
+			return nil
+		})
+	}()
+
+	type key struct{ name, pkg string }
+	seen := map[key]bool{}
+	var diags Diagnostics
+	var emitErr error
+	cut := false
+	for r := range results {
+		// Cancellation is reported once, as Truncated.
+		var errs Diagnostics
+		errs.Add(r.err)
+		for _, err := range errs {
+			if err == context.Canceled || err == context.DeadlineExceeded {
+				cut = true
+				continue
+			}
+			diags.Add(err)
+		}
+		// Unless keepGoing is set, nothing is emitted once a file failed,
+		// not even the tests found in the rest of its package.
+		if !keepGoing && len(diags) > 0 {
+			atomic.StoreInt32(&failed, 1)
+			continue
+		}
+		for _, test := range r.tests {
+			k := key{test.Name, test.Package}
+			if seen[k] || emitErr != nil {
+				continue
+			}
+			seen[k] = true
+			if emitErr = emit(test); emitErr != nil {
+				atomic.StoreInt32(&failed, 1)
+			}
+		}
+	}
+	err := <-walked
+	if emitErr != nil {
+		return emitErr
+	}
+	if _, ok := err.(*Truncated); !ok && cut {
+		err = &Truncated{ctx.Err()}
+	}
+	if err != errStopped {
+		diags.Add(err)
+	}
+	return diags.Err()
+}
+
+// errStopped ends the walk once a file could not be analysed and the
+// listing does not keep going.
+var errStopped = errors.New("stopped")
+
+// Options configure Discover. The zero value walks the current directory
+// with a worker per CPU, without limits, cache or extensions, and stops at
+// the first file that cannot be analysed.
+type Options struct {
+	// Root is the directory to walk.
+	Root string
+	// Jobs is the number of parallel parser workers.
+	Jobs int
+	// MaxFiles and MaxExecution limit the number of walked paths and the
+	// time of the walk. Zero means no limit.
+	MaxFiles     int
+	MaxExecution time.Duration
+	// Excludes are globs of paths to skip and Includes globs of paths to walk
+	// even if they are ignored, in gitignore syntax.
+	Excludes []string
+	Includes []string
+	// Queries are extra tree-sitter queries, see LoadUserQueries.
+	Queries []UserQuery
+	// RunLikes are calls starting subtests besides t.Run, s.Run, c.Run and
+	// Convey, see ParseRunLike.
+	RunLikes []RunLike
+	// Types are the types of tests to list, all of them when nil.
+	Types map[TestType]bool
+	// CacheDir and CacheMode configure the cache of discovery results, see
+	// DefaultCacheDir. The cache is off when CacheMode is empty.
+	CacheDir  string
+	CacheMode CacheMode
+	// KeepGoing lists the tests of the other files when some cannot be
+	// analysed, they are reported in Diagnostics.
+	KeepGoing bool
+}
+
+// Discover lists the tests found under opts.Root, sorted by name and
+// package. When ctx is done or a limit is reached, the tests found until then
+// are returned and the error includes Truncated.
+func Discover(ctx context.Context, opts Options) ([]Test, error) {
+	tests := []Test{}
+	err := Stream(ctx, opts, func(test Test) error {
+		tests = append(tests, test)
+		return nil
+	})
+	sortTests(tests)
+	return tests, err
+}
+
+// Stream is Discover calling emit with every test as soon as it is found,
+// in no particular order. Each test is emitted once, from a single
+// goroutine. An error of emit stops the discovery and is returned.
+func Stream(ctx context.Context, opts Options, emit func(Test) error) error {
+	root := opts.Root
+	if root == "" {
+		root = "."
+	}
+	jobs := opts.Jobs
+	if jobs < 1 {
+		jobs = runtime.NumCPU()
+	}
+	parent := ctx
+	if opts.MaxExecution > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.MaxExecution)
+		defer cancel()
+	}
+	cfg := newConfig(opts.Queries, opts.RunLikes)
+	var cache *resultCache
+	if opts.CacheMode != "" && opts.CacheMode != CacheOff {
+		cache = newResultCache(opts.CacheDir, opts.CacheMode, cfg.version())
+	}
+	ignorer := newPathIgnorer(root, opts.Excludes, opts.Includes)
+	err := cfg.streamTestNames(ctx, root, opts.MaxFiles, jobs, cache, ignorer, opts.KeepGoing, func(test Test) error {
+		if opts.Types != nil && !opts.Types[test.Type] {
+			return nil
+		}
+		return emit(test)
+	})
+	if opts.MaxExecution > 0 && parent.Err() == nil {
+		err = explainTimeout(err, opts.MaxExecution)
+	}
+	return err
+}
+
+// explainTimeout replaces the deadline a truncated listing was stopped by
+// with the execution limit that set it.
+func explainTimeout(err error, maxExecution time.Duration) error {
+	diags, ok := err.(Diagnostics)
+	if !ok {
+		return err
+	}
+	for i, err := range diags {
+		if t, ok := err.(*Truncated); ok && t.Reason == context.DeadlineExceeded {
+			diags[i] = &Truncated{fmt.Errorf("execution time exceeded limit (%s)", maxExecution)}
+		}
+	}
+	return diags
+}
@@ -0,0 +1,737 @@
+package discover
+
+import (
+	"context"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Spit(data string) string {
+	tempname := path.Join(os.TempDir(), "golisttests.tmp")
+	err := ioutil.WriteFile(tempname, []byte(data), 0644)
+	if err != nil {
+		panic(err)
+	}
+	return tempname
+}
+
+// SpitDir writes files into a new temporary directory and returns its path.
+func SpitDir(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "golisttests")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	for name, data := range files {
+		filename := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(filename), 0755))
+		require.NoError(t, ioutil.WriteFile(filename, []byte(data), 0644))
+	}
+	return dir
+}
+
+// Names returns the names of the tests. It accepts the error of a discovery
+// function too, so that its results can be passed as is, and panics on it.
+func Names(tests []Test, errs ...error) []string {
+	for _, err := range errs {
+		if err != nil {
+			panic(err)
+		}
+	}
+	result := []string{}
+	for _, test := range tests {
+		result = append(result, test.Name)
+	}
+	return result
+}
+
+// parseTestNames and the helpers below discover tests without extensions.
+func parseTestNames(ctx context.Context, filename string) ([]Test, error) {
+	return defaultConfig.parsePackageTestNames(ctx, []string{filename})
+}
+
+func parsePackageTestNames(ctx context.Context, filenames []string) ([]Test, error) {
+	return defaultConfig.parsePackageTestNames(ctx, filenames)
+}
+
+func parseTestNamesTreeSitter(ctx context.Context, filename string) ([]Test, error) {
+	return defaultConfig.scanTreeSitter(ctx, filename)
+}
+
+func parseTestNamesGolangAST(ctx context.Context, filename string) ([]Test, error) {
+	return defaultConfig.parsePackageGolangAST(ctx, []string{filename})
+}
+
+// listTestNames collects the tests streamTestNames finds, sorted the way
+// Discover sorts them.
+func listTestNames(ctx context.Context, root string, maxFiles int, jobs int, cache *resultCache, ignorer *pathIgnorer, keepGoing bool) ([]Test, error) {
+	tests := []Test{}
+	err := defaultConfig.streamTestNames(ctx, root, maxFiles, jobs, cache, ignorer, keepGoing, func(test Test) error {
+		tests = append(tests, test)
+		return nil
+	})
+	sortTests(tests)
+	return tests, err
+}
+
+func MustParse(code string) *ast.File {
+	tempname := path.Join(os.TempDir(), "golisttests.tmp")
+	err := ioutil.WriteFile(tempname, []byte(code), 0644)
+	if err != nil {
+		panic(err)
+	}
+	fset := token.NewFileSet()
+	expr, err := parser.ParseFile(fset, tempname, nil, parser.ParseComments)
+	if err != nil {
+		panic(err)
+	}
+	return expr
+}
+
+func FirstFunction(code string) *ast.FuncDecl {
+	for _, decl := range MustParse(code).Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if ok {
+			return fn
+		}
+	}
+	panic("functions not found")
+}
+
+func TestIsSingleArgumentTestingT(t *testing.T) {
+	require.True(t, isSingleArgumentTesting(FirstFunction(`
+package test
+func TestSimple(t *testing.T) {}`), "testing", "T"))
+	require.True(t, isSingleArgumentTesting(FirstFunction(`
+package test
+func NoTestSimple(t *testing.T) {}`), "testing", "T"))
+	require.True(t, isSingleArgumentTesting(FirstFunction(`
+package test
+func NoTestSimple(randomName *testing.T) {}`), "testing", "T"))
+	require.False(t, isSingleArgumentTesting(FirstFunction(`
+package test
+func TestSimple(t *testing.T, more bool) {}`), "testing", "T"))
+	require.False(t, isSingleArgumentTesting(FirstFunction(`
+package test
+func TestSimple(t *testing.B, more bool) {}`), "testing", "T"))
+}
+
+func TestIsReceiverNoArguments(t *testing.T) {
+	require.False(t, hasReceiverAndNoArguments(FirstFunction(`
+package test
+func TestSimple1(t *testing.T) {}`)))
+	require.False(t, hasReceiverAndNoArguments(FirstFunction(`
+package test
+func (p *int) TestSimple2(t *testing.T) {}`)))
+	require.False(t, hasReceiverAndNoArguments(FirstFunction(`
+package test
+func (p int) TestSimple3(t *testing.T) {}`)))
+	require.True(t, hasReceiverAndNoArguments(FirstFunction(`
+package test
+func (p int) TestSimple4() {}`)))
+	require.True(t, hasReceiverAndNoArguments(FirstFunction(`
+package test
+func (p *int) TestSimple5() {}`)))
+	require.True(t, hasReceiverAndNoArguments(FirstFunction(`
+package test
+func (p * int) TestSimple6() {}`)))
+}
+
+func receiverType(t *testing.T, fn *ast.FuncDecl) string {
+	name, err := getReceiverTypeNoStar(fn)
+	require.NoError(t, err)
+	return name
+}
+
+func identNames(idents []*ast.Ident) []string {
+	result := []string{}
+	for _, ident := range idents {
+		result = append(result, ident.Name)
+	}
+	return result
+}
+
+func suiteRunTypes(t *testing.T, fn *ast.FuncDecl) []string {
+	idents, err := findSuiteRunTypes(fn)
+	require.NoError(t, err)
+	return identNames(idents)
+}
+
+func TestGetReceiverTypeNoStar(t *testing.T) {
+	require.Equal(t, "int", receiverType(t, FirstFunction(`
+package test
+func (p int) TestSimple4() {}`)))
+	require.Equal(t, "int", receiverType(t, FirstFunction(`
+package test
+func (p *int) TestSimple5() {}`)))
+	require.Equal(t, "int", receiverType(t, FirstFunction(`
+package test
+func (p * int) TestSimple6() {}`)))
+	require.Equal(t, "RepoSuite", receiverType(t, FirstFunction(`
+package test
+func (s *RepoSuite[T]) TestCreate() {}`)))
+	require.Equal(t, "Pair", receiverType(t, FirstFunction(`
+package test
+func (s Pair[K, V]) TestSwap() {}`)))
+}
+
+func TestFindSuiteRunTypes(t *testing.T) {
+	require.Equal(t,
+		[]string{"aggregatorStarSuite"},
+		suiteRunTypes(t, FirstFunction(`
+package test
+func TestAggregatorStarSuite(t *testing.T) {
+        rand.Seed(0)
+        suite.Run(t, &aggregatorStarSuite{})
+        //_ = &aggregatorStarSuite{}
+}`)))
+	require.Equal(t,
+		[]string{},
+		suiteRunTypes(t, FirstFunction(`
+package test
+func TestAggregatorStarSuite(t *testing.T) {
+        rand.Seed(0)
+        //suite.Run(t, &aggregatorStarSuite{})
+        //_ = &aggregatorStarSuite{}
+}`)))
+	require.Equal(t,
+		[]string{"aggregatorStarSuite", "aggregatorSuite"},
+		suiteRunTypes(t, FirstFunction(`
+package test
+func TestAggregatorStarSuite(t *testing.T) {
+        rand.Seed(0)
+        suite.Run(t, &aggregatorStarSuite{})
+        //_ = &aggregatorStarSuite{}
+        suite.Run(t, &aggregatorSuite{})
+}`)))
+	require.Equal(t,
+		[]string{"aggregatorStarSuite", "aggregatorSuite"},
+		suiteRunTypes(t, FirstFunction(`
+package test
+func TestAggregatorStarSuite(t *testing.T) {
+        rand.Seed(0)
+        suite.Run(t, &aggregatorStarSuite{})
+        suite.Run(t, &aggregatorSuite{})
+        //_ = &aggregatorStarSuite{}
+        suite.Run(t, &aggregatorSuite{})
+        suite.Run(t, &aggregatorStarSuite{})
+}`)))
+	require.Equal(t,
+		[]string{"aggregatorStarSuite"},
+		suiteRunTypes(t, FirstFunction(`
+package test
+func TestAggregatorStarSuite(t *testing.T) {
+        rand.Seed(0)
+        suite.Run(t, new(aggregatorStarSuite))
+        //_ = &aggregatorStarSuite{}
+}`)))
+}
+
+func TestIsSuiteRunner(t *testing.T) {
+	isSuiteRunner := func(fn *ast.FuncDecl) bool {
+		idents, _ := findSuiteRunTypes(fn)
+		return isSimpleTestOf(fn, "testing") && len(idents) > 0
+	}
+	require.False(t, isSuiteRunner(FirstFunction(`
+package test
+func (p int) TestSimple4() {}`)))
+	require.False(t, isSuiteRunner(FirstFunction(`
+package test
+func NotSuiteRunner(t *testing.T) {
+	suite.Run(t, &aggregatorStarSuite{})
+}`)))
+	require.False(t, isSuiteRunner(FirstFunction(`
+package test
+func TestSuiteRunner(t *testing.T) {
+	//suite.Run(t, &aggregatorStarSuite{})
+}`)))
+	require.True(t, isSuiteRunner(FirstFunction(`
+package test
+func TestSuiteRunner(t *testing.T) {
+	suite.Run(t, &aggregatorStarSuite{})
+}`)))
+	require.True(t, isSuiteRunner(FirstFunction(`
+package test
+func TestSuiteRunner(t *testing.T) {
+	suite.Run(t, &aggregatorStarSuite{
+	})
+}`)))
+	require.True(t, isSuiteRunner(FirstFunction(`
+package test
+func TestSuiteRunner(t *testing.T) {
+	suite.Run(t, &aggregatorStarSuite{
+		name: "test",
+	})
+}`)))
+	require.True(t, isSuiteRunner(FirstFunction(`
+package test
+func TestSuiteRunner(t *testing.T) {
+	suite.Run(t, new(aggregatorStarSuite))
+}`)))
+}
+
+func TestParseTestNamesSimple(t *testing.T) {
+	require.Equal(t,
+		[]string{},
+		Names(parseTestNames(context.Background(), Spit(`
+package test
+func (p int) TestSimple1() {}
+`))))
+	require.Equal(t,
+		[]string{},
+		Names(parseTestNames(context.Background(), Spit(`
+package test
+func TestSimple2() {}
+`))))
+	require.Equal(t,
+		[]string{},
+		Names(parseTestNames(context.Background(), Spit(`
+package test
+func TestSimple3(t *something.T) {}
+`))))
+	require.Equal(t,
+		[]string{"TestSimple4"},
+		Names(parseTestNames(context.Background(), Spit(`
+package test
+func TestSimple4(t *testing.T) {}
+`))))
+	require.Equal(t,
+		[]string{"TestSimple5"},
+		Names(parseTestNames(context.Background(), Spit(`
+package test
+func TestSimple5(t * testing.T) {}
+`))))
+}
+
+func TestParseTestNamesSuite(t *testing.T) {
+	require.Equal(t,
+		[]string{
+			"TestSampleSuite",
+			"TestSimple1",
+		},
+		Names(parseTestNames(context.Background(), Spit(`
+package test
+func TestSimple1(t *testing.T) {}
+func TestSampleSuite(t *testing.T) {
+	suite.Run(t, &someType{})
+}
+`))))
+	require.Equal(t,
+		[]string{
+			"TestSampleSuite",
+			"TestSampleSuite/TestValidAfter1",
+			"TestSampleSuite/TestValidAfter2",
+			"TestSampleSuite/TestValidBefore1",
+			"TestSampleSuite/TestValidBefore2",
+			"TestSimple1",
+		},
+		Names(parseTestNames(context.Background(), Spit(`
+package test
+func TestSimple1(t *testing.T) {}
+func (s someType) TestInvalidArgs1(t *testing.T) {}
+func (s *someType) TestInvalidArgs2(t *testing.T) {}
+func (s someType) IncorrectName1() {}
+func (s *someType) IncorrectName2() {}
+func (s someType) TestValidBefore1() {}
+func (s *someType) TestValidBefore2() {}
+func TestSampleSuite(t *testing.T) {
+	suite.Run(t, &someType{})
+}
+func (s someType) TestValidAfter1() {}
+func (s *someType) TestValidAfter2() {}
+func (s unknownType) TestNeverRun1() {}
+func (s *unknownType) TestNeverRun2() {}
+`))))
+	require.Equal(t,
+		[]string{
+			"TestSameTypeDifferentSuite",
+			"TestSameTypeDifferentSuite/TestValidAfter1",
+			"TestSameTypeDifferentSuite/TestValidAfter2",
+			"TestSameTypeDifferentSuite/TestValidBefore1",
+			"TestSameTypeDifferentSuite/TestValidBefore2",
+			"TestSampleSuite",
+			"TestSampleSuite/TestValidAfter1",
+			"TestSampleSuite/TestValidAfter2",
+			"TestSampleSuite/TestValidBefore1",
+			"TestSampleSuite/TestValidBefore2",
+			"TestSimple1",
+		},
+		Names(parseTestNames(context.Background(), Spit(`
+package test
+func TestSimple1(t *testing.T) {}
+func (s someType) TestInvalidArgs1(t *testing.T) {}
+func (s *someType) TestInvalidArgs2(t *testing.T) {}
+func (s someType) IncorrectName1() {}
+func (s *someType) IncorrectName2() {}
+func (s someType) TestValidBefore1() {}
+func (s *someType) TestValidBefore2() {}
+func TestSampleSuite(t *testing.T) {
+	suite.Run(t, &someType{})
+}
+func (s someType) TestValidAfter1() {}
+func (s *someType) TestValidAfter2() {}
+func (s unknownType) TestNeverRun1() {}
+func (s *unknownType) TestNeverRun2() {}
+func TestSameTypeDifferentSuite(t *testing.T) {
+	suite.Run(t, &someType{})
+}
+`))))
+}
+
+func TestParseTestNamesResolveEnvTypeName(t *testing.T) {
+	require.Equal(t,
+		[]string{"TestWeb", "TestWeb/TestValid"},
+		Names(parseTestNames(context.Background(), Spit(`
+package test
+type Env struct {}
+func (e *Env) TestValid() {}
+func TestWeb(t *testing.T) {
+	suite.Run(t, &Env{}) // no resolving here
+}
+`))))
+
+	require.Equal(t,
+		[]string{"TestWeb", "TestWeb/TestValid"},
+		Names(parseTestNames(context.Background(), Spit(`
+package test
+type Env struct {}
+func (e *Env) TestValid() {}
+func TestWeb(t *testing.T) {
+	env := &Env{}
+	suite.Run(t, env) // resolve when already a pointer
+}
+`))))
+
+	require.Equal(t,
+		[]string{"TestWeb", "TestWeb/TestValid"},
+		Names(parseTestNames(context.Background(), Spit(`
+package test
+type Env struct {}
+func (e *Env) TestValid() {}
+func TestWeb(t *testing.T) {
+	env := Env{}
+	suite.Run(t, &env) // resolve when getting a pointer
+}
+`))))
+}
+
+func TestParseTestNamesRecords(t *testing.T) {
+	filename := Spit(`
+package test
+type Env struct {}
+func (e *Env) TestValid() {}
+func TestWeb(t *testing.T) {
+	suite.Run(t, &Env{})
+	t.Run("works", func(t *testing.T) {
+	})
+}
+`)
+	pkg := importPath(filename)
+	tests, err := parseTestNames(context.Background(), filename)
+	require.NoError(t, err)
+	require.Equal(t,
+		[]Test{
+			{Name: "TestWeb", Package: pkg, File: filename, Line: 5, Column: 1, Type: TypeTest, Kind: KindSimple, Discoverer: DiscovererGoAST},
+			{Name: "TestWeb/TestValid", Parent: "TestWeb", Package: pkg, File: filename, Line: 4, Column: 1, Type: TypeTest, Kind: KindSuiteMethod, Discoverer: DiscovererGoAST},
+			{Name: "TestWeb/works", Parent: "TestWeb", Package: pkg, File: filename, Line: 7, Column: 2, Type: TypeTest, Kind: KindSubtest, Discoverer: DiscovererGoAST},
+		},
+		tests)
+}
+
+func TestParseTestNamesOtherTypes(t *testing.T) {
+	tests, err := parseTestNames(context.Background(), Spit(`
+package test
+func TestSimple(t *testing.T) {}
+func BenchmarkSimple(b *testing.B) {
+	b.Run("small", func(b *testing.B) {})
+}
+func BenchmarkInvalid(t *testing.T) {}
+func FuzzSimple(f *testing.F) {}
+func FuzzInvalid(b *testing.B) {}
+func ExampleSimple() {
+	fmt.Println("hello")
+	// Output: hello
+}
+func ExampleUnordered() {
+	// Unordered output:
+	// a
+}
+func ExampleNoOutput() {
+	fmt.Println("hello")
+}
+func ExampleArgs(s string) {
+	// Output:
+}
+`))
+	require.NoError(t, err)
+	types := map[string]TestType{}
+	for _, test := range tests {
+		types[test.Name] = test.Type
+	}
+	require.Equal(t,
+		map[string]TestType{
+			"TestSimple":            TypeTest,
+			"BenchmarkSimple":       TypeBench,
+			"BenchmarkSimple/small": TypeBench,
+			"FuzzSimple":            TypeFuzz,
+			"ExampleSimple":         TypeExample,
+			"ExampleUnordered":      TypeExample,
+		},
+		types)
+}
+
+func TestParseTestTypes(t *testing.T) {
+	types, err := ParseTestTypes("test, bench")
+	require.NoError(t, err)
+	require.Equal(t, map[TestType]bool{TypeTest: true, TypeBench: true}, types)
+	_, err = ParseTestTypes("test,unknown")
+	require.Error(t, err)
+}
+
+func TestParsePackageTestNamesSuiteAcrossFiles(t *testing.T) {
+	dir := SpitDir(t, map[string]string{
+		"suite_test.go": `
+package foo
+type FooSuite struct {}
+func TestFooSuite(t *testing.T) {
+	suite.Run(t, new(FooSuite))
+}
+`,
+		"foo_methods_test.go": `
+package foo
+func (s *FooSuite) TestBar() {}
+`,
+		"external_test.go": `
+package foo_test
+type FooSuite struct {}
+func (s *FooSuite) TestNotRun() {}
+`,
+	})
+	filenames, err := testFilesInDir(dir)
+	require.NoError(t, err)
+	require.Equal(t,
+		[]string{"TestFooSuite", "TestFooSuite/TestBar"},
+		Names(parsePackageTestNames(context.Background(), filenames)))
+
+	tests, err := listTestNames(context.Background(), dir, 0, 2, nil, nil, true)
+	require.NoError(t, err)
+	require.Equal(t, []string{"TestFooSuite", "TestFooSuite/TestBar"}, Names(tests))
+	require.Equal(t, filepath.Join(dir, "foo_methods_test.go"), tests[1].File)
+}
+
+func TestListTestNamesParallel(t *testing.T) {
+	files := map[string]string{}
+	for _, dir := range []string{"a", "b", "c", "d", "e"} {
+		files[dir+"/x_test.go"] = "package " + dir + "\nfunc TestX(t *testing.T) {}\n"
+		files[dir+"/y_test.go"] = "package " + dir + "\nfunc TestY(t *testing.T) {}\n"
+	}
+	dir := SpitDir(t, files)
+	serial, err := listTestNames(context.Background(), dir, 0, 1, nil, nil, true)
+	require.NoError(t, err)
+	require.Len(t, serial, 10)
+	parallel, err := listTestNames(context.Background(), dir, 0, 4, nil, nil, true)
+	require.NoError(t, err)
+	require.Equal(t, serial, parallel)
+
+	_, err = listTestNames(context.Background(), dir, 3, 4, nil, nil, true)
+	require.Error(t, err)
+}
+
+func TestListTestNamesRootFile(t *testing.T) {
+	dir := SpitDir(t, map[string]string{
+		"a/a_test.go": "package a\nfunc TestA(t *testing.T) {}\n",
+		"a/b_test.go": "package a\nfunc TestB(t *testing.T) {}\n",
+	})
+	tests, err := listTestNames(context.Background(), filepath.Join(dir, "a", "a_test.go"), 0, 1, nil, nil, true)
+	require.Equal(t, []string{"TestA"}, Names(tests, err))
+}
+
+func TestTestFilesInDirSymlink(t *testing.T) {
+	dir := SpitDir(t, map[string]string{
+		"a/a_test.go":      "package a\nfunc TestA(t *testing.T) {}\n",
+		"shared/s_test.go": "package a\nfunc TestShared(t *testing.T) {}\n",
+	})
+	require.NoError(t, os.Symlink(filepath.Join(dir, "shared", "s_test.go"), filepath.Join(dir, "a", "s_test.go")))
+	require.NoError(t, os.Symlink(filepath.Join(dir, "missing_test.go"), filepath.Join(dir, "a", "m_test.go")))
+	filenames, err := testFilesInDir(filepath.Join(dir, "a"))
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(dir, "a", "a_test.go"), filepath.Join(dir, "a", "s_test.go")}, filenames)
+}
+
+func TestParseTestNamesTestingImport(t *testing.T) {
+	require.Equal(t,
+		[]string{"BenchmarkAliased", "TestAliased", "TestAliased/works"},
+		Names(parseTestNamesGolangAST(context.Background(), Spit(`
+package test
+import tst "testing"
+func TestAliased(tt *tst.T) {
+	tt.Run("works", func(t *tst.T) {})
+}
+func BenchmarkAliased(b *tst.B) {}
+func TestNotTesting(t *testing.T) {}
+`))))
+	require.Equal(t,
+		[]string{"TestDot"},
+		Names(parseTestNamesGolangAST(context.Background(), Spit(`
+package test
+import . "testing"
+func TestDot(t *T) {}
+`))))
+	require.Equal(t,
+		[]string{"TestOther"},
+		Names(parseTestNamesGolangAST(context.Background(), Spit(`
+package test
+import (
+	"fmt"
+	"testing"
+)
+func TestOther(t *testing.T) {}
+func TestNotTesting(t *T) {}
+`))))
+}
+
+func TestParseTestNamesReportsErrors(t *testing.T) {
+	_, err := getReceiverTypeNoStar(FirstFunction(`
+package test
+func (p (int)) TestParen() {}`))
+	require.Error(t, err)
+
+	idents, err := findSuiteRunTypes(FirstFunction(`
+package test
+func TestSuites(t *testing.T) {
+	suite.Run(t, &struct{}{})
+	suite.Run(t, new(FooSuite))
+}`))
+	require.Error(t, err)
+	require.Equal(t, []string{"FooSuite"}, identNames(idents))
+
+	tests, err := parseTestNamesGolangAST(context.Background(), Spit(`
+package test
+func (p (int)) TestParen() {}
+func TestSimple(t *testing.T) {}
+`))
+	require.EqualError(t, err, os.TempDir()+"/golisttests.tmp:3:1: unsupported receiver type *ast.ParenExpr of TestParen")
+	require.Equal(t, []string{"TestSimple"}, Names(tests))
+}
+
+func TestListTestNamesKeepGoing(t *testing.T) {
+	dir := SpitDir(t, map[string]string{
+		"a/a_test.go": "package a\nfunc TestA(t *testing.T) {}\n",
+		"b/b_test.go": "package b\nfunc TestOK(t *testing.T) {}\nfunc TestB(t *testing.T) {\n",
+	})
+	tests, err := listTestNames(context.Background(), dir, 0, 2, nil, nil, true)
+	require.Error(t, err)
+	require.Len(t, err.(Diagnostics), 1)
+	require.Contains(t, err.Error(), filepath.Join(dir, "b", "b_test.go"))
+	require.Contains(t, Names(tests), "TestA")
+	require.Contains(t, Names(tests), "TestOK")
+
+	_, err = listTestNames(context.Background(), dir, 0, 1, nil, nil, false)
+	require.Error(t, err)
+}
+
+func TestDiscover(t *testing.T) {
+	dir := SpitDir(t, map[string]string{
+		"a/a_test.go": "package a\nfunc TestA(t *testing.T) {\n\tStep(\"one\", t, func() {})\n}\nfunc BenchmarkA(b *testing.B) {}\n",
+		"b/b_test.go": "package b\nfunc TestB(t *testing.T) {}\n",
+	})
+	tests, err := Discover(context.Background(), Options{
+		Root:     dir,
+		Excludes: []string{"b"},
+		RunLikes: []RunLike{{Name: "Step", NameArg: 0, BodyArg: -1}},
+		Types:    map[TestType]bool{TypeTest: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"TestA", "TestA/one"}, Names(tests))
+
+	_, err = Discover(context.Background(), Options{Root: dir, MaxFiles: 2})
+	require.True(t, IsTruncated(err))
+	require.Contains(t, err.Error(), "truncated: number of files exceeded limit (2)")
+}
+
+func TestStream(t *testing.T) {
+	dir := SpitDir(t, map[string]string{
+		"a/a_test.go": "package a\nfunc TestA(t *testing.T) {\n\tt.Run(\"one\", func(t *testing.T) {})\n}\nfunc BenchmarkA(b *testing.B) {}\n",
+		"b/b_test.go": "package b\nfunc TestB(t *testing.T) {}\n",
+	})
+	var streamed []Test
+	err := Stream(context.Background(), Options{Root: dir, Types: map[TestType]bool{TypeTest: true}}, func(test Test) error {
+		streamed = append(streamed, test)
+		return nil
+	})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"TestA", "TestA/one", "TestB"}, Names(streamed))
+
+	stop := errors.New("stop")
+	calls := 0
+	err = Stream(context.Background(), Options{Root: dir, Jobs: 1}, func(test Test) error {
+		calls++
+		return stop
+	})
+	require.Equal(t, stop, err)
+	require.Equal(t, 1, calls)
+
+	// Without KeepGoing, the tests of a package that failed are not emitted.
+	broken := SpitDir(t, map[string]string{
+		"a/a_test.go": "package a\nfunc TestA(t *testing.T) {}\n",
+		"b/b_test.go": "package b\nfunc TestOK(t *testing.T) {}\nfunc TestB(t *testing.T) {\n",
+	})
+	streamed = nil
+	err = Stream(context.Background(), Options{Root: broken, Jobs: 1}, func(test Test) error {
+		streamed = append(streamed, test)
+		return nil
+	})
+	require.Error(t, err)
+	require.Equal(t, []string{"TestA"}, Names(streamed))
+}
+
+func TestDiscoverCancelled(t *testing.T) {
+	dir := SpitDir(t, map[string]string{
+		"a/a_test.go": "package a\nfunc TestA(t *testing.T) {}\n",
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests, err := Discover(ctx, Options{Root: dir})
+	require.True(t, IsTruncated(err))
+	require.Empty(t, tests)
+
+	filename := filepath.Join(dir, "a", "a_test.go")
+	_, err = parseTestNamesGolangAST(ctx, filename)
+	require.Equal(t, context.Canceled, err)
+	_, err = parseTestNamesTreeSitter(ctx, filename)
+	require.Equal(t, context.Canceled, err)
+	tests, err = parseTestNames(context.Background(), filename)
+	require.NoError(t, err)
+	require.False(t, IsTruncated(err))
+	require.Equal(t, []string{"TestA"}, Names(tests))
+}
+
+func TestStreamCancelledMidway(t *testing.T) {
+	files := map[string]string{}
+	all := []string{}
+	for _, pkg := range []string{"a", "b", "c", "d", "e", "f"} {
+		name := "Test_" + pkg
+		files[pkg+"/"+pkg+"_test.go"] = "package " + pkg + "\nfunc " + name + "(t *testing.T) {}\n"
+		all = append(all, name)
+	}
+	dir := SpitDir(t, files)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var streamed []Test
+	err := Stream(ctx, Options{Root: dir, Jobs: 1}, func(test Test) error {
+		streamed = append(streamed, test)
+		cancel()
+		return nil
+	})
+	require.True(t, IsTruncated(err))
+	require.NotEmpty(t, streamed)
+	require.Less(t, len(streamed), len(all))
+	require.Subset(t, all, Names(streamed))
+}
@@ -0,0 +1,112 @@
+package discover
+
+import (
+	"context"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+
+	_ "embed"
+)
+
+//go:embed ginkgo.scm
+var queryGinkgo []byte
+
+// ginkgoImports are the import paths of the Ginkgo versions.
+var ginkgoImports = map[string]bool{
+	"github.com/onsi/ginkgo":    true,
+	"github.com/onsi/ginkgo/v2": true,
+}
+
+var ginkgoContainer = regexp.MustCompile(`(Describe|Context|When|DescribeTable)$`)
+
+type ginkgoNode struct {
+	text      string
+	container bool
+	node      *sitter.Node
+}
+
+// scanGinkgo finds Ginkgo containers (Describe, Context, When,
+// DescribeTable) and specs (It, Specify, Entry). A test is named by its
+// full text: the texts of the enclosing containers and its own text joined
+// by spaces, which is what Ginkgo reports and matches -ginkgo.focus against.
+// Only files importing Ginkgo are scanned, and only calls of its functions
+// are accepted.
+func scanGinkgo(ctx context.Context, input []byte, root *sitter.Node) ([]Test, error) {
+	tests := []Test{}
+	// The imports may parse even if the rest of the file does not.
+	file, _ := parser.ParseFile(token.NewFileSet(), "", input, parser.ImportsOnly)
+	if file == nil {
+		return tests, nil
+	}
+	pkg := importName(file, ginkgoImports, "ginkgo")
+	if pkg == "" {
+		return tests, nil
+	}
+	var nodes []*ginkgoNode
+	byRange := map[[2]uint32]*ginkgoNode{}
+	err := scanQuery(ctx, input, queryGinkgo, root, func(m *sitter.QueryMatch, c captureNodes) {
+		if !isGinkgoFunc(c["spec.func"], input, pkg) {
+			return
+		}
+		// Unlike go test, Ginkgo keeps texts as they are.
+		text, err := strconv.Unquote(c["spec.text"].Content(input))
+		if err != nil {
+			return
+		}
+		n := &ginkgoNode{
+			text:      text,
+			container: ginkgoContainer.MatchString(c["spec.func"].Content(input)),
+			node:      c["spec.location"],
+		}
+		nodes = append(nodes, n)
+		byRange[[2]uint32{n.node.StartByte(), n.node.EndByte()}] = n
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, n := range nodes {
+		var path []string
+		for p := n.node.Parent(); p != nil; p = p.Parent() {
+			if p.Type() != "call_expression" {
+				continue
+			}
+			if outer, ok := byRange[[2]uint32{p.StartByte(), p.EndByte()}]; ok && outer.container {
+				path = append([]string{outer.text}, path...)
+			}
+		}
+		point := n.node.StartPoint()
+		kind := KindGinkgoSpec
+		if n.container {
+			kind = KindGinkgoContainer
+		}
+		tests = append(tests, Test{
+			Name:       strings.Join(append(path, n.text), " "),
+			Parent:     strings.Join(path, " "),
+			Line:       int(point.Row) + 1,
+			Column:     int(point.Column) + 1,
+			Type:       TypeGinkgo,
+			Kind:       kind,
+			Discoverer: DiscovererTreeSitter,
+		})
+	}
+	return tests, nil
+}
+
+// isGinkgoFunc reports whether the function of a call is qualified by pkg,
+// the name Ginkgo is imported by, or unqualified when Ginkgo is dot-imported.
+func isGinkgoFunc(fn *sitter.Node, input []byte, pkg string) bool {
+	switch fn.Type() {
+	case "identifier":
+		return pkg == "."
+	case "selector_expression":
+		operand := fn.ChildByFieldName("operand")
+		return operand != nil && operand.Content(input) == pkg
+	}
+	return false
+}
@@ -0,0 +1,6 @@
+(call_expression
+  function: [(identifier) (selector_expression)] @spec.func
+  (#match? @spec.func "^([A-Za-z_][A-Za-z0-9_]*[.])?[FPX]?(Describe|Context|When|DescribeTable|It|Specify|Entry)$")
+  arguments: (argument_list
+    .
+    [(interpreted_string_literal) (raw_string_literal)] @spec.text)) @spec.location
@@ -0,0 +1,94 @@
+package discover
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestScanGinkgo(t *testing.T) {
+	tests, err := parseTestNamesTreeSitter(context.Background(), Spit(`
+package books_test
+import (
+	. "github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+)
+func TestBooks(t *testing.T) {
+	gomega.RegisterFailHandler(Fail)
+	RunSpecs(t, "Books Suite")
+}
+var _ = Describe("Books", func() {
+	Context("when empty", func() {
+		It("returns zero", func() {})
+		FIt("is "+"empty", func() {})
+	})
+	When(`+"`loaded`"+`, func() {
+		DescribeTable("counts",
+			func(n int) {},
+			Entry("one book", 1),
+		)
+		Specify("works", func() {})
+	})
+	It("is not nested", func() {})
+})
+`))
+	require.NoError(t, err)
+	var names, parents []string
+	for _, test := range tests {
+		require.Equal(t, TypeGinkgo, test.Type)
+		names = append(names, test.Name)
+		parents = append(parents, test.Parent)
+	}
+	require.Equal(t,
+		[]string{
+			"Books",
+			"Books when empty",
+			"Books when empty returns zero",
+			"Books loaded",
+			"Books loaded counts",
+			"Books loaded counts one book",
+			"Books loaded works",
+			"Books is not nested",
+		},
+		names)
+	require.Equal(t,
+		[]string{
+			"",
+			"Books",
+			"Books when empty",
+			"Books",
+			"Books loaded",
+			"Books loaded counts",
+			"Books loaded",
+			"Books",
+		},
+		parents)
+	require.Equal(t, KindGinkgoContainer, tests[0].Kind)
+	require.Equal(t, KindGinkgoSpec, tests[2].Kind)
+	require.Equal(t, 13, tests[2].Line)
+}
+
+func TestScanGinkgoImport(t *testing.T) {
+	tests, err := parseTestNamesTreeSitter(context.Background(), Spit(`
+package web
+import "testing"
+func TestWeb(t *testing.T) {
+	logger.When("user logged in")
+	span.Context("request")
+	doc.Describe("a thing")
+}
+`))
+	require.Empty(t, Names(tests, err))
+
+	tests, err = parseTestNamesTreeSitter(context.Background(), Spit(`
+package books_test
+import g "github.com/onsi/ginkgo"
+var _ = g.Describe("Books", func() {
+	span.Context("request")
+	Context("unqualified", func() {})
+	g.It("works", func() {})
+})
+`))
+	require.Equal(t, []string{"Books", "Books works"}, Names(tests, err))
+}
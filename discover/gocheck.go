@@ -0,0 +1,101 @@
+package discover
+
+import (
+	"fmt"
+	"go/ast"
+	"strconv"
+)
+
+// gocheckImports are the import paths gocheck is published under.
+var gocheckImports = map[string]bool{
+	"gopkg.in/check.v1":         true,
+	"github.com/go-check/check": true,
+}
+
+// gocheckImportName returns the name the file refers to gocheck by: the
+// import alias, "." for a dot import or "check" otherwise. It returns ""
+// when the file does not import gocheck.
+func gocheckImportName(file *ast.File) string {
+	return importName(file, gocheckImports, "check")
+}
+
+// importName returns the name the file refers to a package imported by one
+// of paths by: the import alias, "." for a dot import or name otherwise. It
+// returns "" when the file does not import the package.
+func importName(file *ast.File, paths map[string]bool, name string) string {
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil || !paths[path] {
+			continue
+		}
+		if spec.Name != nil {
+			if spec.Name.Name == "_" {
+				return ""
+			}
+			return spec.Name.Name
+		}
+		return name
+	}
+	return ""
+}
+
+// isGocheckFunc reports whether expr refers to the gocheck function name,
+// where checkPkg is the name gocheck is imported by.
+func isGocheckFunc(expr ast.Expr, checkPkg string, name string) bool {
+	switch f := expr.(type) {
+	case *ast.SelectorExpr:
+		pkg, ok := f.X.(*ast.Ident)
+		return ok && pkg.Name == checkPkg && f.Sel.Name == name
+	case *ast.Ident:
+		return checkPkg == "." && f.Name == name
+	}
+	return false
+}
+
+// findGocheckSuites returns the identifiers of the suite types registered
+// with check.Suite anywhere in the file, usually in `var _ = Suite(...)`.
+// Registrations no type can be found in are reported in the error.
+func findGocheckSuites(file *ast.File, checkPkg string) ([]*ast.Ident, error) {
+	var result []*ast.Ident
+	var diags Diagnostics
+	if checkPkg == "" {
+		return result, nil
+	}
+	ast.Inspect(file, func(node ast.Node) bool {
+		call, ok := node.(*ast.CallExpr)
+		if !ok || len(call.Args) != 1 || !isGocheckFunc(call.Fun, checkPkg, "Suite") {
+			return true
+		}
+		ident, err := getFirstIdent(call.Args[0])
+		if err != nil {
+			diags.Add(fmt.Errorf("check.Suite: %v", err))
+			return true
+		}
+		result = append(result, ident)
+		return true
+	})
+	return result, diags.Err()
+}
+
+// isGocheckRunner reports whether the test hands over to gocheck by calling
+// check.TestingT.
+func isGocheckRunner(fn *ast.FuncDecl, checkPkg string) bool {
+	if checkPkg == "" || fn.Body == nil {
+		return false
+	}
+	found := false
+	ast.Inspect(fn.Body, func(node ast.Node) bool {
+		if call, ok := node.(*ast.CallExpr); ok && isGocheckFunc(call.Fun, checkPkg, "TestingT") {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// isGocheckTest reports whether fn is a gocheck test method, i.e.
+// func (s *MySuite) TestX(c *check.C).
+func isGocheckTest(fn *ast.FuncDecl, checkPkg string) bool {
+	return checkPkg != "" && hasReceiver(fn) && isTestName(fn.Name.Name) &&
+		isSingleArgumentTesting(fn, checkPkg, "C")
+}
@@ -0,0 +1,55 @@
+package discover
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTestNamesGocheck(t *testing.T) {
+	tests, err := parseTestNamesGolangAST(context.Background(), Spit(`
+package legacy
+import (
+	"testing"
+	. "gopkg.in/check.v1"
+)
+func Test(t *testing.T) { TestingT(t) }
+type MySuite struct{}
+var _ = Suite(&MySuite{})
+func (s *MySuite) SetUpTest(c *C) {}
+func (s *MySuite) TestHello(c *C) {}
+func (s *MySuite) TestNoC() {}
+type Unregistered struct{}
+func (s *Unregistered) TestSkipped(c *C) {}
+`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"MySuite.TestHello", "Test"}, Names(tests))
+	require.Equal(t, "Test", tests[0].Parent)
+	require.Equal(t, TypeGocheck, tests[0].Type)
+	require.Equal(t, KindGocheckMethod, tests[0].Kind)
+}
+
+func TestParsePackageTestNamesGocheckAcrossFiles(t *testing.T) {
+	dir := SpitDir(t, map[string]string{
+		"main_test.go": `
+package legacy
+import (
+	"testing"
+	check "gopkg.in/check.v1"
+)
+func TestAll(t *testing.T) { check.TestingT(t) }
+var _ = check.Suite(new(DBSuite))
+`,
+		"db_test.go": `
+package legacy
+import "gopkg.in/check.v1"
+type DBSuite struct{}
+func (s *DBSuite) TestQuery(c *check.C) {}
+`,
+	})
+	tests, err := parsePackageTestNames(context.Background(), []string{dir + "/db_test.go", dir + "/main_test.go"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"DBSuite.TestQuery", "TestAll"}, Names(tests))
+	require.Equal(t, "TestAll", tests[0].Parent)
+}
@@ -0,0 +1,267 @@
+package discover
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreFiles are read in every walked directory, later files taking
+// precedence over earlier ones.
+var ignoreFiles = []string{".gitignore", ".ignore"}
+
+// isIgnoredByGoTool reports whether the go tool ignores a directory with the
+// given name when matching ./... patterns. node_modules is not ignored by
+// the go tool, but never holds packages we want to test either.
+func isIgnoredByGoTool(name string) bool {
+	if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+		return true
+	}
+	switch name {
+	case "testdata", "vendor", "node_modules":
+		return true
+	}
+	return false
+}
+
+// ignoreRule is a single gitignore-style pattern.
+type ignoreRule struct {
+	pattern string
+	negate  bool
+	dirOnly bool
+}
+
+// newIgnoreRule parses a line of an ignore file. It returns false for blank
+// lines and comments.
+func newIgnoreRule(line string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+	var rule ignoreRule
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	}
+	line = strings.TrimPrefix(line, `\`)
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+	if strings.Contains(line, "/") {
+		line = strings.TrimPrefix(line, "/")
+	} else {
+		line = "**/" + line
+	}
+	rule.pattern = line
+	return rule, true
+}
+
+// match reports whether the rule matches a slash-separated path relative to
+// the directory the rule was defined in.
+func (r ignoreRule) match(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	return matchGlob(strings.Split(r.pattern, "/"), strings.Split(rel, "/"))
+}
+
+// matchGlob matches path elements against pattern elements, where ** matches
+// any number of elements.
+func matchGlob(pattern []string, elems []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(elems); i++ {
+				if matchGlob(pattern[1:], elems[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(elems) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], elems[0]); err != nil || !ok {
+			return false
+		}
+		pattern, elems = pattern[1:], elems[1:]
+	}
+	return len(elems) == 0
+}
+
+func readIgnoreRules(filename string) []ignoreRule {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rule, ok := newIgnoreRule(scanner.Text()); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// ancestorRules are the rules of the ignore files of a directory above the
+// walked tree, prefix is the slash-separated root relative to it.
+type ancestorRules struct {
+	prefix string
+	rules  []ignoreRule
+}
+
+// readAncestorRules reads the ignore files of the directories above root up
+// to the repository root, the directory containing .git, outermost first.
+// Nothing is read when root is not in a repository.
+func readAncestorRules(root string) []ancestorRules {
+	dir, err := filepath.Abs(root)
+	if err != nil {
+		return nil
+	}
+	var result []ancestorRules
+	prefix := ""
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return result
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		prefix = path.Join(filepath.Base(dir), prefix)
+		dir = parent
+		var rules []ignoreRule
+		for _, name := range ignoreFiles {
+			rules = append(rules, readIgnoreRules(filepath.Join(dir, name))...)
+		}
+		if len(rules) > 0 {
+			result = append([]ancestorRules{{prefix, rules}}, result...)
+		}
+	}
+}
+
+// pathIgnorer decides which paths of a walk are skipped. Directories ignored
+// by the go tool, and paths matched by .gitignore and .ignore files found in
+// the walked tree and its ancestors up to the repository root are skipped.
+// Exclude patterns skip more paths, include patterns override every other
+// rule. Patterns without a slash match the name at any depth, other
+// patterns match the path relative to the root.
+type pathIgnorer struct {
+	root      string
+	excludes  []ignoreRule
+	includes  []ignoreRule
+	ancestors []ancestorRules
+	rules     map[string][]ignoreRule
+}
+
+func newPathIgnorer(root string, excludes []string, includes []string) *pathIgnorer {
+	parse := func(patterns []string) []ignoreRule {
+		var result []ignoreRule
+		for _, pattern := range patterns {
+			if rule, ok := newIgnoreRule(pattern); ok {
+				result = append(result, rule)
+			}
+		}
+		return result
+	}
+	return &pathIgnorer{
+		root:      root,
+		excludes:  parse(excludes),
+		includes:  parse(includes),
+		ancestors: readAncestorRules(root),
+		rules:     map[string][]ignoreRule{},
+	}
+}
+
+// isIgnored reports whether path is skipped. Directories must be passed
+// before their contents, as it reads their ignore files.
+func (ig *pathIgnorer) isIgnored(name string, isDir bool) bool {
+	rel, err := filepath.Rel(ig.root, name)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	if rel == "." {
+		ig.enter(rel, name)
+		return false
+	}
+	if ig.ignored(rel, isDir) {
+		return true
+	}
+	if isDir {
+		ig.enter(rel, name)
+	}
+	return false
+}
+
+func (ig *pathIgnorer) ignored(rel string, isDir bool) bool {
+	for _, rule := range ig.includes {
+		if rule.match(rel, isDir) {
+			return false
+		}
+	}
+	for _, rule := range ig.excludes {
+		if rule.match(rel, isDir) {
+			return true
+		}
+	}
+	if isDir && isIgnoredByGoTool(path.Base(rel)) {
+		return true
+	}
+	ignored := false
+	for _, ancestor := range ig.ancestors {
+		for _, rule := range ancestor.rules {
+			if rule.match(ancestor.prefix+"/"+rel, isDir) {
+				ignored = !rule.negate
+			}
+		}
+	}
+	for dir := "."; ; {
+		dirRel := rel
+		if dir != "." {
+			dirRel = strings.TrimPrefix(rel, dir+"/")
+		}
+		for _, rule := range ig.rules[dir] {
+			if rule.match(dirRel, isDir) {
+				ignored = !rule.negate
+			}
+		}
+		next := strings.IndexByte(dirRel, '/')
+		if next < 0 {
+			return ignored
+		}
+		if dir == "." {
+			dir = dirRel[:next]
+		} else {
+			dir = dir + "/" + dirRel[:next]
+		}
+	}
+}
+
+func (ig *pathIgnorer) enter(rel string, dir string) {
+	var rules []ignoreRule
+	for _, name := range ignoreFiles {
+		rules = append(rules, readIgnoreRules(filepath.Join(dir, name))...)
+	}
+	if len(rules) > 0 {
+		ig.rules[rel] = rules
+	}
+}
+
+// filter returns the files that are not ignored.
+func (ig *pathIgnorer) filter(filenames []string) []string {
+	var result []string
+	for _, filename := range filenames {
+		if !ig.isIgnored(filename, false) {
+			result = append(result, filename)
+		}
+	}
+	return result
+}
@@ -0,0 +1,88 @@
+package discover
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIgnoreRuleMatch(t *testing.T) {
+	match := func(pattern string, rel string, isDir bool) bool {
+		rule, ok := newIgnoreRule(pattern)
+		require.True(t, ok, pattern)
+		return rule.match(rel, isDir)
+	}
+	require.True(t, match("build", "build", true))
+	require.True(t, match("build", "a/b/build", false))
+	require.True(t, match("build/", "a/build", true))
+	require.False(t, match("build/", "a/build", false))
+	require.True(t, match("/gen", "gen", true))
+	require.False(t, match("/gen", "a/gen", true))
+	require.True(t, match("a/*/c", "a/b/c", true))
+	require.False(t, match("a/*/c", "a/b/b/c", true))
+	require.True(t, match("a/**/c", "a/b/b/c", true))
+	require.True(t, match("*_gen_test.go", "pkg/x_gen_test.go", false))
+
+	_, ok := newIgnoreRule("# comment")
+	require.False(t, ok)
+	_, ok = newIgnoreRule("   ")
+	require.False(t, ok)
+}
+
+func TestListTestNamesIgnored(t *testing.T) {
+	test := func(name string) string {
+		return "package p\nfunc " + name + "(t *testing.T) {}\n"
+	}
+	dir := SpitDir(t, map[string]string{
+		"a/a_test.go":              test("TestA"),
+		"vendor/v/v_test.go":       test("TestVendor"),
+		"a/testdata/d_test.go":     test("TestTestdata"),
+		"_old/o_test.go":           test("TestUnderscore"),
+		".git/g_test.go":           test("TestGit"),
+		"node_modules/n/n_test.go": test("TestNodeModules"),
+		".gitignore":               "build/\n*_gen_test.go\n!keep_gen_test.go\n",
+		"build/b_test.go":          test("TestBuild"),
+		"a/x_gen_test.go":          test("TestGenerated"),
+		"a/keep_gen_test.go":       test("TestKept"),
+		"c/.ignore":                "/skip\n",
+		"c/skip/s_test.go":         test("TestSkipped"),
+		"c/c_test.go":              test("TestC"),
+		"d/d_test.go":              test("TestD"),
+	})
+
+	tests, err := listTestNames(context.Background(), dir, 0, 1, nil, nil, true)
+	require.NoError(t, err)
+	require.Equal(t, []string{"TestA", "TestC", "TestD", "TestKept"}, Names(tests))
+
+	tests, err = listTestNames(context.Background(), dir, 0, 1, nil, newPathIgnorer(dir, []string{"d"}, []string{"vendor", "build"}), true)
+	require.NoError(t, err)
+	require.Equal(t, []string{"TestA", "TestBuild", "TestC", "TestKept", "TestVendor"}, Names(tests))
+}
+
+func TestListTestNamesIgnoredByAncestors(t *testing.T) {
+	test := func(name string) string {
+		return "package p\nfunc " + name + "(t *testing.T) {}\n"
+	}
+	dir := SpitDir(t, map[string]string{
+		".git/HEAD":              "ref: refs/heads/main\n",
+		".gitignore":             "gen/\n/pkg/a/skip_test.go\n",
+		"pkg/.ignore":            "*_old_test.go\n",
+		"pkg/a/a_test.go":        test("TestA"),
+		"pkg/a/skip_test.go":     test("TestSkipped"),
+		"pkg/a/x_old_test.go":    test("TestOld"),
+		"pkg/a/gen/g_test.go":    test("TestGenerated"),
+		"pkg/a/b/b_test.go":      test("TestB"),
+		"pkg/a/b/skip_test.go":   test("TestNotSkipped"),
+		"outside/.gitignore":     "*\n",
+		"outside/repo/.git/HEAD": "ref: refs/heads/main\n",
+		"outside/repo/r_test.go": test("TestRepo"),
+	})
+
+	tests, err := listTestNames(context.Background(), filepath.Join(dir, "pkg", "a"), 0, 1, nil, nil, true)
+	require.Equal(t, []string{"TestA", "TestB", "TestNotSkipped"}, Names(tests, err))
+
+	tests, err = listTestNames(context.Background(), filepath.Join(dir, "outside", "repo"), 0, 1, nil, nil, true)
+	require.Equal(t, []string{"TestRepo"}, Names(tests, err))
+}
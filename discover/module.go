@@ -0,0 +1,104 @@
+package discover
+
+import (
+	"bytes"
+	"go/build"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+type goModule struct {
+	Dir  string
+	Path string
+}
+
+var modules = struct {
+	sync.Mutex
+	byDir map[string]*goModule
+}{byDir: make(map[string]*goModule)}
+
+// modulePath returns the module path from the module line of a go.mod file.
+func modulePath(gomod []byte) string {
+	for len(gomod) > 0 {
+		line := gomod
+		gomod = nil
+		if i := bytes.IndexByte(line, '\n'); i >= 0 {
+			line, gomod = line[:i], line[i+1:]
+		}
+		if i := bytes.Index(line, []byte("//")); i >= 0 {
+			line = line[:i]
+		}
+		line = bytes.TrimSpace(line)
+		if !bytes.HasPrefix(line, []byte("module")) {
+			continue
+		}
+		line = bytes.TrimSpace(line[len("module"):])
+		if len(line) == 0 {
+			continue
+		}
+		if line[0] == '"' || line[0] == '`' {
+			path, err := strconv.Unquote(string(line))
+			if err != nil {
+				return ""
+			}
+			return path
+		}
+		return string(line)
+	}
+	return ""
+}
+
+// findModule returns the module that contains dir, or nil if dir is not
+// inside a module.
+func findModule(dir string) *goModule {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil
+	}
+	modules.Lock()
+	defer modules.Unlock()
+	return findModuleLocked(dir)
+}
+
+func findModuleLocked(dir string) *goModule {
+	if m, ok := modules.byDir[dir]; ok {
+		return m
+	}
+	var m *goModule
+	data, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+	if err == nil {
+		if path := modulePath(data); path != "" {
+			m = &goModule{Dir: dir, Path: path}
+		}
+	} else if parent := filepath.Dir(dir); parent != dir {
+		m = findModuleLocked(parent)
+	}
+	modules.byDir[dir] = m
+	return m
+}
+
+// importPath returns the import path of the package that filename belongs to.
+// Outside of a module it falls back to the directory of the file in a form
+// that go test still accepts.
+func importPath(filename string) string {
+	dir := filepath.Dir(filename)
+	if m := findModule(dir); m != nil {
+		abs, err := filepath.Abs(dir)
+		if err == nil {
+			rel, err := filepath.Rel(m.Dir, abs)
+			if err == nil {
+				if rel == "." {
+					return m.Path
+				}
+				return m.Path + "/" + filepath.ToSlash(rel)
+			}
+		}
+	}
+	dir = filepath.ToSlash(dir)
+	if filepath.IsAbs(dir) || build.IsLocalImport(dir) {
+		return dir
+	}
+	return "./" + dir
+}
@@ -0,0 +1,33 @@
+package discover
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestModulePath(t *testing.T) {
+	require.Equal(t, "golisttests", modulePath([]byte(`module golisttests
+
+go 1.16
+`)))
+	require.Equal(t, "example.com/foo", modulePath([]byte(`// comment
+module "example.com/foo" // trailing
+`)))
+	require.Equal(t, "", modulePath([]byte(`go 1.16`)))
+}
+
+func TestImportPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golisttests")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "pkg", "foo"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/mono\n"), 0644))
+
+	require.Equal(t, "example.com/mono", importPath(filepath.Join(dir, "a_test.go")))
+	require.Equal(t, "example.com/mono/pkg/foo", importPath(filepath.Join(dir, "pkg", "foo", "a_test.go")))
+	require.Equal(t, "golisttests/discover", importPath("discover_test.go"))
+}
@@ -0,0 +1,101 @@
+package discover
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// subtestName returns the name go test gives to a subtest named by the Go
+// string literal. Anything but a string literal is used verbatim.
+func subtestName(literal string) string {
+	if s, err := strconv.Unquote(literal); err == nil {
+		literal = s
+	}
+	return rewriteSubtestName(literal)
+}
+
+// rewriteSubtestName rewrites a subtest name the way the testing package
+// does: spaces become underscores and non-printable characters are escaped.
+func rewriteSubtestName(s string) string {
+	b := []byte{}
+	for _, r := range s {
+		switch {
+		case isSpace(r):
+			b = append(b, '_')
+		case !strconv.IsPrint(r):
+			s := strconv.QuoteRune(r)
+			b = append(b, s[1:len(s)-1]...)
+		default:
+			b = append(b, string(r)...)
+		}
+	}
+	return string(b)
+}
+
+// isSpace is the definition of a space used by the testing package, which is
+// not the same as unicode.IsSpace.
+func isSpace(r rune) bool {
+	if r < 0x2000 {
+		switch r {
+		case '\t', '\n', '\v', '\f', '\r', ' ', 0x85, 0xA0, 0x1680:
+			return true
+		}
+	} else {
+		if r <= 0x200a {
+			return true
+		}
+		switch r {
+		case 0x2028, 0x2029, 0x202f, 0x205f, 0x3000:
+			return true
+		}
+	}
+	return false
+}
+
+// subtestNamer makes subtest names unique the way the testing package does,
+// appending #01, #02, ... to repeated names. Names must be passed in the
+// order the subtests run.
+type subtestNamer struct {
+	subNames map[string]int
+}
+
+func newSubtestNamer() *subtestNamer {
+	return &subtestNamer{subNames: map[string]int{}}
+}
+
+// unique returns the full name of the subtest of parent with the rewritten
+// name subname.
+func (n *subtestNamer) unique(parent string, subname string) string {
+	name := fmt.Sprintf("%s/%s", parent, subname)
+	empty := subname == ""
+	for {
+		next, exists := n.subNames[name]
+		if !empty && !exists {
+			n.subNames[name] = 1
+			return name
+		}
+		n.subNames[name] = next + 1
+		name = fmt.Sprintf("%s#%02d", name, next)
+		empty = false
+	}
+}
+
+// uniqueSubtestNames makes names of subtests unique, assuming they run in
+// the order they are declared in.
+func uniqueSubtestNames(tests []Test) {
+	sort.SliceStable(tests, func(i, j int) bool {
+		if tests[i].Line != tests[j].Line {
+			return tests[i].Line < tests[j].Line
+		}
+		return tests[i].Column < tests[j].Column
+	})
+	namer := newSubtestNamer()
+	for i, test := range tests {
+		if test.Parent == "" {
+			continue
+		}
+		tests[i].Name = namer.unique(test.Parent, strings.TrimPrefix(test.Name, test.Parent+"/"))
+	}
+}
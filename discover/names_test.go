@@ -0,0 +1,31 @@
+package discover
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubtestName(t *testing.T) {
+	require.Equal(t, "device_event", subtestName(`"device event"`))
+	require.Equal(t, `say_"hi"`, subtestName(`"say \"hi\""`))
+	require.Equal(t, "a_b", subtestName(`"a\tb"`))
+	require.Equal(t, `a\x00b`, subtestName(`"a\x00b"`))
+	require.Equal(t, `raw\t_string`, subtestName("`raw\\t string`"))
+	require.Equal(t, "two_lines", subtestName("`two\nlines`"))
+	require.Equal(t, "привет_мир", subtestName(`"привет мир"`))
+	require.Equal(t, "a_b", subtestName(`"a b"`))
+	require.Equal(t, "ident", subtestName(`ident`))
+}
+
+func TestSubtestNamer(t *testing.T) {
+	n := newSubtestNamer()
+	require.Equal(t, "TestX/a", n.unique("TestX", "a"))
+	require.Equal(t, "TestX/a#01", n.unique("TestX", "a"))
+	require.Equal(t, "TestX/a#02", n.unique("TestX", "a"))
+	require.Equal(t, "TestY/a", n.unique("TestY", "a"))
+	require.Equal(t, "TestX/#00", n.unique("TestX", ""))
+	require.Equal(t, "TestX/#01", n.unique("TestX", ""))
+	// A literal name that looks like a generated one is still made unique.
+	require.Equal(t, "TestX/a#01#01", n.unique("TestX", "a#01"))
+}
@@ -0,0 +1,162 @@
+package discover
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/golang"
+)
+
+// UserQuery is a tree-sitter query that teaches the tool about custom
+// test helpers. Every match must capture:
+//
+//	@func.name      the top-level test function, e.g. TestWeb
+//	@test.name      the string literal naming the subtest
+//
+// and may capture:
+//
+//	@parent.name    a string literal naming an intermediate subtest; the test
+//	                is then listed as func.name/parent.name/test.name
+//	@test.location  the node the test is located at instead of @test.name
+//
+// Every pattern of the query is checked when it is loaded, matches that miss
+// a required capture anyway, e.g. an optional one, are skipped. Predicates
+// #match? and #eq? are supported.
+type UserQuery struct {
+	Filename string
+	Source   []byte
+}
+
+// DefaultQueryDir returns the directory under $XDG_CONFIG_HOME (or the
+// platform equivalent) that user queries are loaded from.
+func DefaultQueryDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "golisttests", "queries"), nil
+}
+
+// LoadUserQueries loads every .scm file of dir (which may not exist) and the
+// given files, checking that they compile and follow the capture contract.
+func LoadUserQueries(dir string, filenames []string) ([]UserQuery, error) {
+	var all []string
+	if dir != "" {
+		matches, err := filepath.Glob(filepath.Join(dir, "*.scm"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(matches)
+		all = append(all, matches...)
+	}
+	all = append(all, filenames...)
+
+	var result []UserQuery
+	for _, filename := range all {
+		source, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkUserQuery(source); err != nil {
+			return nil, fmt.Errorf("%s: %v", filename, err)
+		}
+		result = append(result, UserQuery{Filename: filename, Source: source})
+	}
+	return result, nil
+}
+
+// userQueryCaptures are the captures every pattern of a user query needs.
+var userQueryCaptures = []string{"func.name", "test.name"}
+
+func checkUserQuery(source []byte) error {
+	if err := checkQueryCaptures(source); err != nil {
+		return err
+	}
+	patterns := queryPatterns(source)
+	if len(patterns) < 2 {
+		return nil
+	}
+	for i, pattern := range patterns {
+		if err := checkQueryCaptures(pattern); err != nil {
+			return fmt.Errorf("pattern %d: %v", i+1, err)
+		}
+	}
+	return nil
+}
+
+// checkQueryCaptures checks that a query compiles and has the required
+// captures.
+func checkQueryCaptures(source []byte) error {
+	q, err := sitter.NewQuery(source, golang.GetLanguage())
+	if err != nil {
+		return err
+	}
+	defer q.Close()
+	captures := map[string]bool{}
+	for i := uint32(0); i < q.CaptureCount(); i++ {
+		captures[q.CaptureNameForId(i)] = true
+	}
+	for _, name := range userQueryCaptures {
+		if !captures[name] {
+			return fmt.Errorf("query does not capture @%s", name)
+		}
+	}
+	return nil
+}
+
+// queryPatterns splits a query into its top-level patterns, each with the
+// captures and quantifiers that follow it. The bindings do not tell which
+// captures a pattern has, so patterns are checked on their own.
+func queryPatterns(source []byte) [][]byte {
+	var result [][]byte
+	depth, start := 0, -1
+	for i := 0; i < len(source); i++ {
+		switch source[i] {
+		case ';':
+			for i < len(source) && source[i] != '\n' {
+				i++
+			}
+		case '"':
+			for i++; i < len(source) && source[i] != '"'; i++ {
+				if source[i] == '\\' {
+					i++
+				}
+			}
+		case '(', '[':
+			if depth == 0 {
+				if start >= 0 {
+					result = append(result, source[start:i])
+				}
+				start = i
+			}
+			depth++
+		case ')', ']':
+			depth--
+		}
+	}
+	if start >= 0 {
+		result = append(result, source[start:])
+	}
+	return result
+}
+
+func scanUserQuery(ctx context.Context, input []byte, query []byte, root *sitter.Node) ([]Test, error) {
+	tests := []Test{}
+	err := scanQuery(ctx, input, query, root, func(m *sitter.QueryMatch, c captureNodes) {
+		test, ok := newTreeSitterTest(c, input, KindSubtest)
+		if !ok {
+			return
+		}
+		if parent, ok := c["parent.name"]; ok {
+			test.Parent = fmt.Sprintf("%s/%s", c["func.name"].Content(input), subtestName(parent.Content(input)))
+			test.Name = fmt.Sprintf("%s/%s", test.Parent, subtestName(c["test.name"].Content(input)))
+		}
+		tests = append(tests, test)
+	})
+	return tests, err
+}
@@ -0,0 +1,122 @@
+package discover
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const queryRunCase = `
+(function_declaration
+  name: (identifier) @func.name
+  (#match? @func.name "^Test")
+  body: (block
+    (call_expression
+      function: (identifier) @helper
+      (#match? @helper "^runCase$")
+      arguments: (argument_list
+        (identifier)
+        (interpreted_string_literal) @test.name)) @test.location))
+`
+
+const queryGroupCase = `
+(function_declaration
+  name: (identifier) @func.name
+  body: (block
+    (call_expression
+      function: (identifier) @helper
+      (#match? @helper "^groupCase$")
+      arguments: (argument_list
+        (interpreted_string_literal) @parent.name
+        (interpreted_string_literal) @test.name))))
+`
+
+func TestLoadUserQueries(t *testing.T) {
+	dir := SpitDir(t, map[string]string{
+		"queries/run_case.scm": queryRunCase,
+		"queries/readme.txt":   "not a query",
+		"group_case.scm":       queryGroupCase,
+		"invalid.scm":          "(function_declaration",
+		"no_captures.scm":      "(function_declaration name: (identifier) @func.name)",
+		"one_pattern_without.scm": queryRunCase + `
+; a comment (with parentheses)
+(call_expression
+  function: (identifier) @func.name
+  arguments: (argument_list (identifier) @other))
+`,
+	})
+	queries, err := LoadUserQueries(dir+"/queries", []string{dir + "/group_case.scm"})
+	require.NoError(t, err)
+	require.Len(t, queries, 2)
+	require.Equal(t, dir+"/queries/run_case.scm", queries[0].Filename)
+
+	_, err = LoadUserQueries("", []string{dir + "/invalid.scm"})
+	require.Error(t, err)
+	_, err = LoadUserQueries("", []string{dir + "/no_captures.scm"})
+	require.EqualError(t, err, dir+"/no_captures.scm: query does not capture @test.name")
+	_, err = LoadUserQueries("", []string{dir + "/one_pattern_without.scm"})
+	require.EqualError(t, err, dir+"/one_pattern_without.scm: pattern 2: query does not capture @test.name")
+	queries, err = LoadUserQueries(dir+"/missing", nil)
+	require.NoError(t, err)
+	require.Empty(t, queries)
+}
+
+func TestScanUserQueries(t *testing.T) {
+	cfg := newConfig([]UserQuery{{Source: []byte(queryRunCase)}, {Source: []byte(queryGroupCase)}}, nil)
+	tests, err := cfg.parsePackageTestNames(context.Background(), []string{Spit(`
+package test
+func TestWeb(t *testing.T) {
+	runCase(t, "first case", func(t *testing.T) {})
+	groupCase("group", "second")
+}
+`)})
+	require.NoError(t, err)
+	require.Equal(t,
+		[]string{"TestWeb", "TestWeb/first_case", "TestWeb/group/second"},
+		Names(tests))
+	require.Equal(t, "TestWeb/group", tests[2].Parent)
+	require.Equal(t, 4, tests[1].Line)
+	require.Equal(t, 2, tests[1].Column)
+}
+
+func TestScanUserQueryInvalidPattern(t *testing.T) {
+	cfg := newConfig([]UserQuery{{Filename: "broken.scm", Source: []byte(`
+(call_expression
+  function: (identifier) @func.name
+  (#match? @func.name "[")
+  arguments: (argument_list (interpreted_string_literal) @test.name))
+`)}}, nil)
+	tests, err := cfg.scanTreeSitter(context.Background(), Spit(`
+package test
+func TestWeb(t *testing.T) {
+	t.Run("works", func(t *testing.T) {})
+	run("case")
+}
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "broken.scm: invalid #match? pattern")
+	require.Equal(t, []string{"TestWeb/works"}, Names(tests))
+}
+
+func TestScanUserQueryOptionalCapture(t *testing.T) {
+	query := []byte(`
+(function_declaration
+  name: (identifier) @func.name
+  body: (block
+    (call_expression
+      function: (identifier) @helper
+      (#match? @helper "^run$")
+      arguments: (argument_list
+        (interpreted_string_literal)? @test.name))))
+`)
+	require.NoError(t, checkUserQuery(query))
+	tests, err := newConfig([]UserQuery{{Filename: "optional.scm", Source: query}}, nil).scanTreeSitter(context.Background(), Spit(`
+package test
+func TestWeb(t *testing.T) {
+	run()
+	run("case")
+}
+`))
+	require.Equal(t, []string{"TestWeb/case"}, Names(tests, err))
+}
@@ -0,0 +1,134 @@
+package discover
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// RunLike describes a call that starts a named subtest, like t.Run.
+type RunLike struct {
+	// Name is the name of the function or method, e.g. Run or Convey.
+	Name string
+	// Method requires the call to be made on the test, e.g. t.Run, or on a
+	// value made from it by New, e.g. c := qt.New(t); c.Run. Otherwise the
+	// function may be called by any name qualified or not, e.g. Convey or
+	// convey.Convey.
+	Method bool
+	// NameArg and BodyArg are positions of the arguments holding the name
+	// of the subtest and the function running it. Negative positions count
+	// from the end, -1 is the last argument.
+	NameArg int
+	BodyArg int
+}
+
+// defaultRunLikes recognises t.Run (also testify's s.Run and quicktest's
+// c.Run) and GoConvey's Convey blocks.
+var defaultRunLikes = []RunLike{
+	{Name: "Run", Method: true, NameArg: 0, BodyArg: 1},
+	{Name: "Convey", NameArg: 0, BodyArg: -1},
+	{Name: "FocusConvey", NameArg: 0, BodyArg: -1},
+}
+
+// ParseRunLike parses a run-like call given as [.]name[:nameArg:bodyArg],
+// where a leading dot stands for a method called on the test, e.g. .Run or
+// Convey:0:-1. The name and the body are the first two arguments by default.
+func ParseRunLike(spec string) (RunLike, error) {
+	parts := strings.Split(spec, ":")
+	r := RunLike{Name: parts[0], NameArg: 0, BodyArg: 1}
+	if strings.HasPrefix(r.Name, ".") {
+		r.Name = r.Name[1:]
+		r.Method = true
+	}
+	if !token.IsIdentifier(r.Name) {
+		return r, fmt.Errorf("invalid run-like call %q: %q is not a function name", spec, r.Name)
+	}
+	switch len(parts) {
+	case 1:
+		return r, nil
+	case 3:
+		var err error
+		if r.NameArg, err = strconv.Atoi(parts[1]); err != nil {
+			return r, fmt.Errorf("invalid run-like call %q: %v", spec, err)
+		}
+		if r.BodyArg, err = strconv.Atoi(parts[2]); err != nil {
+			return r, fmt.Errorf("invalid run-like call %q: %v", spec, err)
+		}
+		return r, nil
+	}
+	return r, fmt.Errorf("invalid run-like call %q: want [.]name[:nameArg:bodyArg]", spec)
+}
+
+func (r RunLike) String() string {
+	name := r.Name
+	if r.Method {
+		name = "." + name
+	}
+	return fmt.Sprintf("%s:%d:%d", name, r.NameArg, r.BodyArg)
+}
+
+// match returns the name and the body arguments of call if it is this
+// run-like call. recvs are the names the test is known by.
+func (r RunLike) match(call *ast.CallExpr, recvs map[string]bool) (ast.Expr, ast.Expr, bool) {
+	name := argAt(call, r.NameArg)
+	body := argAt(call, r.BodyArg)
+	if name == nil || body == nil {
+		return nil, nil, false
+	}
+	switch fun := call.Fun.(type) {
+	case *ast.SelectorExpr:
+		if fun.Sel.Name != r.Name || r.Method && !isTestValue(fun.X, recvs) {
+			return nil, nil, false
+		}
+	case *ast.Ident:
+		if r.Method || fun.Name != r.Name {
+			return nil, nil, false
+		}
+	default:
+		return nil, nil, false
+	}
+	return name, body, true
+}
+
+func argAt(call *ast.CallExpr, i int) ast.Expr {
+	if i < 0 {
+		i += len(call.Args)
+	}
+	if i < 0 || i >= len(call.Args) {
+		return nil
+	}
+	return call.Args[i]
+}
+
+// isTestValue reports whether expr is the test or a value made from it by
+// New, like quicktest's qt.New(t).
+func isTestValue(expr ast.Expr, recvs map[string]bool) bool {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return recvs[e.Name]
+	case *ast.CallExpr:
+		return isTestWrapper(e, recvs)
+	}
+	return false
+}
+
+func isTestWrapper(call *ast.CallExpr, recvs map[string]bool) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "New" || len(call.Args) != 1 {
+		return false
+	}
+	return isTestValue(call.Args[0], recvs)
+}
+
+// matchRunLike returns the name and the body arguments of call if it is
+// any of the run-like calls.
+func matchRunLike(runLikes []RunLike, call *ast.CallExpr, recvs map[string]bool) (ast.Expr, ast.Expr, bool) {
+	for _, r := range runLikes {
+		if name, body, ok := r.match(call, recvs); ok {
+			return name, body, true
+		}
+	}
+	return nil, nil, false
+}
@@ -0,0 +1,88 @@
+package discover
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRunLike(t *testing.T) {
+	r, err := ParseRunLike(".Run")
+	require.NoError(t, err)
+	require.Equal(t, RunLike{Name: "Run", Method: true, NameArg: 0, BodyArg: 1}, r)
+
+	r, err = ParseRunLike("Convey:0:-1")
+	require.NoError(t, err)
+	require.Equal(t, RunLike{Name: "Convey", NameArg: 0, BodyArg: -1}, r)
+
+	_, err = ParseRunLike("Convey:0")
+	require.Error(t, err)
+	_, err = ParseRunLike("a.b")
+	require.Error(t, err)
+	_, err = ParseRunLike("Step:x:1")
+	require.Error(t, err)
+}
+
+func TestParseTestNamesConvey(t *testing.T) {
+	require.Equal(t,
+		[]string{
+			"TestSpec",
+			"TestSpec/Given_a_number",
+			"TestSpec/Given_a_number/When_it_is_doubled",
+			"TestSpec/Given_a_number/When_it_is_doubled/It_is_even",
+			"TestSpec/Given_a_number/With_a_context",
+		},
+		Names(parseTestNamesGolangAST(context.Background(), Spit(`
+package test
+func TestSpec(t *testing.T) {
+	Convey("Given a number", t, func() {
+		x := 1
+		Convey("When it is doubled", func() {
+			x *= 2
+			Convey("It is even", func() {
+				So(x%2, ShouldEqual, 0)
+			})
+		})
+		convey.Convey("With a context", func(c convey.C) {})
+	})
+}
+`))))
+}
+
+func TestParseTestNamesQuicktest(t *testing.T) {
+	require.Equal(t,
+		[]string{
+			"TestQt",
+			"TestQt/direct",
+			"TestQt/nested",
+			"TestQt/nested/inner",
+			"TestQt/wrapped",
+		},
+		Names(parseTestNamesGolangAST(context.Background(), Spit(`
+package test
+func TestQt(t *testing.T) {
+	c := qt.New(t)
+	c.Run("wrapped", func(c *qt.C) {})
+	qt.New(t).Run("direct", func(c *qt.C) {})
+	c.Run("nested", func(c *qt.C) {
+		c.Run("inner", func(c *qt.C) {})
+	})
+	other.Run("not a subtest", func(c *qt.C) {})
+}
+`))))
+}
+
+func TestParseTestNamesCustomRunLike(t *testing.T) {
+	cfg := newConfig(nil, []RunLike{{Name: "Step", Method: true, NameArg: 1, BodyArg: 0}})
+	require.Equal(t,
+		[]string{"TestSteps", "TestSteps/login", "TestSteps/login/submit"},
+		Names(cfg.parsePackageGolangAST(context.Background(), []string{Spit(`
+package test
+func TestSteps(t *testing.T) {
+	t.Step(func(t *testing.T) {
+		t.Run("submit", func(t *testing.T) {})
+	}, "login")
+}
+`)})))
+}
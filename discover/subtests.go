@@ -0,0 +1,325 @@
+package discover
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// foundSubtest is a subtest found in the body of a test function. Name is
+// the full name of the subtest, Parent is the full name of the test or
+// subtest that runs it.
+type foundSubtest struct {
+	Name   string
+	Parent string
+	Pos    token.Pos
+	Kind   Kind
+}
+
+// testingParamName returns the name of the single parameter of a test
+// function, or an empty string if it is unnamed.
+func testingParamName(fn *ast.FuncDecl) string {
+	return singleParamName(fn.Type)
+}
+
+func singleParamName(fn *ast.FuncType) string {
+	params := fn.Params.List
+	if len(params) != 1 || len(params[0].Names) != 1 || params[0].Names[0].Name == "_" {
+		return ""
+	}
+	return params[0].Names[0].Name
+}
+
+// findSubtests finds subtests of the test function fn named testName. Run
+// calls, and other run-like calls, are found at any depth of nesting, inside
+// any statement, and the subtests they start are searched recursively,
+// giving names like TestX/a/b. A subtest name is either a string literal or
+// comes from a table: t.Run(x.<field>, ...) inside a range loop is followed
+// back to the ranged slice or map literal and the <field> values of its
+// elements are collected, whatever the variables and the field are called.
+// Ranging over a map with t.Run(key, ...) collects the keys of the map
+// literal.
+func findSubtests(fn *ast.FuncDecl, testName string, runLikes []RunLike) []foundSubtest {
+	recv := testingParamName(fn)
+	if recv == "" || fn.Body == nil {
+		return nil
+	}
+	f := &subtestFinder{namer: newSubtestNamer(), runLikes: runLikes}
+	f.find(fn.Body, map[string]bool{recv: true}, testName)
+	return f.result
+}
+
+// findSuiteSubtests finds subtests of the testify suite method fn that runs
+// as testName, i.e. s.Run calls on the method's receiver.
+func findSuiteSubtests(fn *ast.FuncDecl, testName string, runLikes []RunLike) []foundSubtest {
+	if fn.Recv == nil || len(fn.Recv.List) != 1 || fn.Body == nil {
+		return nil
+	}
+	names := fn.Recv.List[0].Names
+	if len(names) != 1 || names[0].Name == "_" {
+		return nil
+	}
+	f := &subtestFinder{namer: newSubtestNamer(), runLikes: runLikes}
+	f.find(fn.Body, map[string]bool{names[0].Name: true}, testName)
+	return f.result
+}
+
+type subtestFinder struct {
+	namer    *subtestNamer
+	runLikes []RunLike
+	result   []foundSubtest
+}
+
+// find finds run-like calls in body made on any of recvs, the names the
+// test is known by, or on values made from them, like c := qt.New(t).
+func (f *subtestFinder) find(body ast.Node, recvs map[string]bool, parent string) {
+	var stack []ast.Node
+	ast.Inspect(body, func(node ast.Node) bool {
+		if node == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		if assign, ok := node.(*ast.AssignStmt); ok {
+			for i, rhs := range assign.Rhs {
+				call, ok := rhs.(*ast.CallExpr)
+				if !ok || i >= len(assign.Lhs) || !isTestWrapper(call, recvs) {
+					continue
+				}
+				if ident, ok := assign.Lhs[i].(*ast.Ident); ok && ident.Name != "_" {
+					recvs[ident.Name] = true
+				}
+			}
+		}
+		call, ok := node.(*ast.CallExpr)
+		if !ok {
+			stack = append(stack, node)
+			return true
+		}
+		nameArg, bodyArg, ok := matchRunLike(f.runLikes, call, recvs)
+		if !ok {
+			stack = append(stack, node)
+			return true
+		}
+		lit, _ := bodyArg.(*ast.FuncLit)
+		for _, subtest := range f.names(nameArg, call.Pos(), stack) {
+			subtest.Name = f.namer.unique(parent, subtest.Name)
+			subtest.Parent = parent
+			f.result = append(f.result, subtest)
+			if lit == nil {
+				continue
+			}
+			// Bodies of testify's suite.Run and of Convey take no arguments
+			// and keep using the test they are nested in.
+			if len(lit.Type.Params.List) == 0 {
+				f.find(lit.Body, copyRecvs(recvs), subtest.Name)
+			} else if nested := singleParamName(lit.Type); nested != "" {
+				f.find(lit.Body, map[string]bool{nested: true}, subtest.Name)
+			}
+		}
+		return false
+	})
+}
+
+func copyRecvs(recvs map[string]bool) map[string]bool {
+	result := make(map[string]bool, len(recvs))
+	for name := range recvs {
+		result[name] = true
+	}
+	return result
+}
+
+// names returns subtests started by a run-like call at pos, which is nested
+// in the nodes of the stack, with their own rewritten names. arg is the
+// argument holding the name.
+func (f *subtestFinder) names(arg ast.Expr, pos token.Pos, stack []ast.Node) []foundSubtest {
+	switch arg := arg.(type) {
+	case *ast.BasicLit:
+		if arg.Kind == token.STRING {
+			return []foundSubtest{{Name: subtestName(arg.Value), Pos: pos, Kind: KindSubtest}}
+		}
+	case *ast.Ident:
+		for i := len(stack) - 1; i >= 0; i-- {
+			if loop, ok := stack[i].(*ast.RangeStmt); ok {
+				if key, ok := loop.Key.(*ast.Ident); ok && key.Name == arg.Name {
+					return tableKeys(loop.X)
+				}
+				if value, ok := loop.Value.(*ast.Ident); ok && value.Name == arg.Name {
+					return tableValues(loop.X)
+				}
+			}
+		}
+	case *ast.SelectorExpr:
+		x, ok := arg.X.(*ast.Ident)
+		if !ok {
+			return nil
+		}
+		for i := len(stack) - 1; i >= 0; i-- {
+			if loop, ok := stack[i].(*ast.RangeStmt); ok {
+				if value, ok := loop.Value.(*ast.Ident); ok && value.Name == x.Name {
+					return tableFieldValues(loop.X, arg.Sel.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// tableValues returns the string elements of the slice literal that expr
+// evaluates to.
+func tableValues(expr ast.Expr) []foundSubtest {
+	lit, ok := resolveCompositeLit(expr)
+	if !ok {
+		return nil
+	}
+	if _, ok := lit.Type.(*ast.ArrayType); !ok {
+		return nil
+	}
+	var result []foundSubtest
+	for _, elt := range lit.Elts {
+		if value, ok := elt.(*ast.BasicLit); ok && value.Kind == token.STRING {
+			result = append(result, foundSubtest{Name: subtestName(value.Value), Pos: value.Pos(), Kind: KindTableCase})
+		}
+	}
+	return result
+}
+
+// tableKeys returns string keys of the map literal that expr evaluates to.
+func tableKeys(expr ast.Expr) []foundSubtest {
+	lit, ok := resolveCompositeLit(expr)
+	if !ok {
+		return nil
+	}
+	if _, ok := lit.Type.(*ast.MapType); !ok {
+		return nil
+	}
+	var result []foundSubtest
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		if key, ok := kv.Key.(*ast.BasicLit); ok && key.Kind == token.STRING {
+			result = append(result, foundSubtest{Name: subtestName(key.Value), Pos: kv.Pos(), Kind: KindTableCase})
+		}
+	}
+	return result
+}
+
+// tableFieldValues returns string values of the field in elements of the
+// slice or map literal that expr evaluates to.
+func tableFieldValues(expr ast.Expr, field string) []foundSubtest {
+	lit, ok := resolveCompositeLit(expr)
+	if !ok {
+		return nil
+	}
+	index := -1
+	if fields := structFields(elementType(lit.Type)); fields != nil {
+		index = fieldIndex(fields, field)
+	}
+	var result []foundSubtest
+	for _, elt := range lit.Elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			elt = kv.Value
+		}
+		elem, ok := unaddr(elt).(*ast.CompositeLit)
+		if !ok {
+			continue
+		}
+		var value ast.Expr
+		for i, e := range elem.Elts {
+			if kv, ok := e.(*ast.KeyValueExpr); ok {
+				if key, ok := kv.Key.(*ast.Ident); ok && key.Name == field {
+					value = kv.Value
+				}
+			} else if i == index {
+				value = e
+			}
+		}
+		if lit, ok := value.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			result = append(result, foundSubtest{Name: subtestName(lit.Value), Pos: elem.Pos(), Kind: KindTableCase})
+		}
+	}
+	return result
+}
+
+// resolveCompositeLit follows an identifier to the composite literal it was
+// declared or assigned with.
+func resolveCompositeLit(expr ast.Expr) (*ast.CompositeLit, bool) {
+	switch e := expr.(type) {
+	case *ast.CompositeLit:
+		return e, true
+	case *ast.ParenExpr:
+		return resolveCompositeLit(e.X)
+	case *ast.Ident:
+		if e.Obj == nil {
+			return nil, false
+		}
+		switch decl := e.Obj.Decl.(type) {
+		case *ast.AssignStmt:
+			for i, lhs := range decl.Lhs {
+				if ident, ok := lhs.(*ast.Ident); ok && ident.Name == e.Name && i < len(decl.Rhs) {
+					return resolveCompositeLit(decl.Rhs[i])
+				}
+			}
+		case *ast.ValueSpec:
+			for i, name := range decl.Names {
+				if name.Name == e.Name && i < len(decl.Values) {
+					return resolveCompositeLit(decl.Values[i])
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+func unaddr(expr ast.Expr) ast.Expr {
+	if u, ok := expr.(*ast.UnaryExpr); ok && u.Op == token.AND {
+		return u.X
+	}
+	return expr
+}
+
+// elementType returns the element type of a slice, array or map type.
+func elementType(expr ast.Expr) ast.Expr {
+	switch t := expr.(type) {
+	case *ast.ArrayType:
+		return t.Elt
+	case *ast.MapType:
+		return t.Value
+	}
+	return nil
+}
+
+// structFields returns the fields of a struct type, following type names
+// and pointers declared in the same file.
+func structFields(expr ast.Expr) *ast.FieldList {
+	switch t := expr.(type) {
+	case *ast.StructType:
+		return t.Fields
+	case *ast.StarExpr:
+		return structFields(t.X)
+	case *ast.Ident:
+		if t.Obj != nil {
+			if spec, ok := t.Obj.Decl.(*ast.TypeSpec); ok {
+				return structFields(spec.Type)
+			}
+		}
+	}
+	return nil
+}
+
+func fieldIndex(fields *ast.FieldList, name string) int {
+	index := 0
+	for _, field := range fields.List {
+		if len(field.Names) == 0 {
+			index++
+			continue
+		}
+		for _, ident := range field.Names {
+			if ident.Name == name {
+				return index
+			}
+			index++
+		}
+	}
+	return -1
+}
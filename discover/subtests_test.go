@@ -0,0 +1,231 @@
+package discover
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTestNamesTableDataFlow(t *testing.T) {
+	require.Equal(t,
+		[]string{"TestCases", "TestCases/empty_input", "TestCases/one_item"},
+		Names(parseTestNamesGolangAST(context.Background(), Spit(`
+package test
+func TestCases(t *testing.T) {
+	cases := []struct {
+		desc  string
+		input []int
+	}{
+		{desc: "empty input"},
+		{desc: "one item", input: []int{1}},
+	}
+	for _, tt := range cases {
+		t.Run(tt.desc, func(t *testing.T) {})
+	}
+}
+`))))
+
+	require.Equal(t,
+		[]string{"TestNamedType", "TestNamedType/first", "TestNamedType/second", "TestNamedType/third"},
+		Names(parseTestNamesGolangAST(context.Background(), Spit(`
+package test
+type testCase struct {
+	in       int
+	scenario string
+}
+var testCases = []*testCase{
+	{1, "first"},
+	&testCase{in: 2, scenario: "second"},
+	{scenario: "third"},
+	{scenario: computed()},
+}
+func TestNamedType(tt *testing.T) {
+	for i, tc := range testCases {
+		_ = i
+		tt.Run(tc.scenario, func(t *testing.T) {})
+	}
+}
+`))))
+
+	require.Equal(t,
+		[]string{"TestInline", "TestInline/a", "TestInline/b"},
+		Names(parseTestNamesGolangAST(context.Background(), Spit(`
+package test
+func TestInline(t *testing.T) {
+	for _, c := range []struct{ title string }{{"a"}, {title: "b"}} {
+		c := c
+		t.Run(c.title, func(t *testing.T) {})
+	}
+}
+`))))
+
+	require.Equal(t,
+		[]string{"TestNotRun"},
+		Names(parseTestNamesGolangAST(context.Background(), Spit(`
+package test
+func TestNotRun(t *testing.T) {
+	tests := []struct{ name string }{{"a"}}
+	for _, tc := range tests {
+		other.Run(tc.name, func(t *testing.T) {})
+		fmt.Println(tc.name)
+	}
+}
+`))))
+}
+
+func TestParseTestNamesTableLocation(t *testing.T) {
+	tests, err := parseTestNamesGolangAST(context.Background(), Spit(`
+package test
+func TestCases(t *testing.T) {
+	cases := []struct{ desc string }{
+		{desc: "first"},
+	}
+	for _, tt := range cases {
+		t.Run(tt.desc, func(t *testing.T) {})
+	}
+}
+`))
+	require.NoError(t, err)
+	require.Equal(t, "TestCases/first", tests[1].Name)
+	require.Equal(t, "TestCases", tests[1].Parent)
+	require.Equal(t, KindTableCase, tests[1].Kind)
+	require.Equal(t, 5, tests[1].Line)
+	require.Equal(t, 3, tests[1].Column)
+}
+
+func TestParseTestNamesMapTable(t *testing.T) {
+	require.Equal(t,
+		[]string{"TestMap", "TestMap/empty_input", "TestMap/one_item"},
+		Names(parseTestNamesGolangAST(context.Background(), Spit(`
+package test
+func TestMap(t *testing.T) {
+	tests := map[string]struct {
+		input []int
+	}{
+		"empty input": {},
+		"one item":    {input: []int{1}},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_ = tc
+		})
+	}
+}
+`))))
+
+	require.Equal(t,
+		[]string{"TestMapField", "TestMapField/first", "TestMapField/second"},
+		Names(parseTestNamesGolangAST(context.Background(), Spit(`
+package test
+func TestMapField(t *testing.T) {
+	tests := map[int]struct {
+		name string
+	}{
+		1: {name: "first"},
+		2: {"second"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {})
+	}
+}
+`))))
+
+	require.Equal(t,
+		[]string{"TestNotKeys"},
+		Names(parseTestNamesGolangAST(context.Background(), Spit(`
+package test
+func TestNotKeys(t *testing.T) {
+	tests := []string{"a", "b"}
+	for i := range tests {
+		t.Run(i, func(t *testing.T) {})
+	}
+}
+`))))
+}
+
+func TestParseTestNamesTableDuplicates(t *testing.T) {
+	require.Equal(t,
+		[]string{"TestDuplicates", "TestDuplicates/#00", "TestDuplicates/same", "TestDuplicates/same#01"},
+		Names(parseTestNamesGolangAST(context.Background(), Spit(`
+package test
+func TestDuplicates(t *testing.T) {
+	tests := []struct{ name string }{{"same"}, {"same"}, {""}}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {})
+	}
+}
+`))))
+}
+
+func TestParseTestNamesNestedSubtests(t *testing.T) {
+	require.Equal(t,
+		[]string{
+			"TestNested",
+			"TestNested/a",
+			"TestNested/a/b",
+			"TestNested/a/b/c",
+			"TestNested/a/in_if",
+			"TestNested/in_for",
+			"TestNested/in_switch",
+			"TestNested/table_x",
+			"TestNested/table_x/child",
+			"TestNested/table_y",
+			"TestNested/table_y/child",
+		},
+		Names(parseTestNamesGolangAST(context.Background(), Spit(`
+package test
+func TestNested(t *testing.T) {
+	t.Run("a", func(t *testing.T) {
+		t.Run("b", func(tt *testing.T) {
+			tt.Run("c", func(t *testing.T) {})
+		})
+		if true {
+			t.Run("in if", func(t *testing.T) {})
+		}
+	})
+	for i := 0; i < 2; i++ {
+		t.Run("in for", func(t *testing.T) {})
+	}
+	switch {
+	default:
+		t.Run("in switch", func(t *testing.T) {})
+	}
+	for _, name := range []string{"table x", "table y"} {
+		t.Run(name, func(t *testing.T) {
+			t.Run("child", func(t *testing.T) {})
+		})
+	}
+}
+`))))
+}
+
+func TestParseTestNamesSuiteSubtests(t *testing.T) {
+	require.Equal(t,
+		[]string{
+			"TestFooSuite",
+			"TestFooSuite/TestBar",
+			"TestFooSuite/TestBar/case_one",
+			"TestFooSuite/TestBar/case_one/nested",
+			"TestFooSuite/TestBar/table_a",
+			"TestFooSuite/TestBar/table_b",
+			"TestFooSuite/TestBaz",
+		},
+		Names(parseTestNamesGolangAST(context.Background(), Spit(`
+package test
+type FooSuite struct{ suite.Suite }
+func TestFooSuite(t *testing.T) {
+	suite.Run(t, new(FooSuite))
+}
+func (s *FooSuite) TestBar() {
+	s.Run("case one", func() {
+		s.Run("nested", func() {})
+	})
+	for _, tc := range []struct{ name string }{{"table a"}, {"table b"}} {
+		s.Run(tc.name, func() {})
+	}
+	other.Run("not a subtest", func() {})
+}
+func (*FooSuite) TestBaz() {}
+`))))
+}
@@ -0,0 +1,144 @@
+package discover
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// suiteRunner is a suite type identifier passed to suite.Run by a test.
+type suiteRunner struct {
+	ident    *ast.Ident
+	testName string
+}
+
+// promotedSuiteMethods returns test methods the suite type t inherits from
+// the structs it embeds. Methods declared on t itself are found in the AST
+// and are not returned.
+func promotedSuiteMethods(t types.Type) []*types.Func {
+	if t == nil {
+		return nil
+	}
+	var result []*types.Func
+	methods := types.NewMethodSet(types.NewPointer(t))
+	for i := 0; i < methods.Len(); i++ {
+		selection := methods.At(i)
+		if len(selection.Index()) < 2 {
+			continue
+		}
+		fn, ok := selection.Obj().(*types.Func)
+		if !ok || !isTestName(fn.Name()) {
+			continue
+		}
+		if sig, ok := fn.Type().(*types.Signature); ok && sig.Params().Len() == 0 {
+			result = append(result, fn)
+		}
+	}
+	return result
+}
+
+// needsModuleSources reports whether any suite type embeds a type that could
+// not be resolved, which happens when it comes from another package of the
+// module.
+func needsModuleSources(resolver *typeResolver, runners []suiteRunner) bool {
+	for _, runner := range runners {
+		t := resolver.resolveType(runner.ident)
+		if t == nil {
+			continue
+		}
+		s, ok := t.Underlying().(*types.Struct)
+		if !ok {
+			continue
+		}
+		for i := 0; i < s.NumFields(); i++ {
+			field := s.Field(i)
+			if !field.Embedded() {
+				continue
+			}
+			ft := field.Type()
+			if p, ok := ft.(*types.Pointer); ok {
+				ft = p.Elem()
+			}
+			if ft == types.Typ[types.Invalid] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// moduleImporter imports packages of the module by type-checking their
+// sources, which are found by mapping the import path onto the module
+// directory. Other packages are imported from export data.
+type moduleImporter struct {
+	ctx      context.Context
+	fset     *token.FileSet
+	module   *goModule
+	fallback types.Importer
+	packages map[string]*types.Package
+}
+
+// newModuleImporter returns an importer of the module packages that stops
+// importing once ctx is done.
+func newModuleImporter(ctx context.Context, fset *token.FileSet, module *goModule) *moduleImporter {
+	return &moduleImporter{
+		ctx:      ctx,
+		fset:     fset,
+		module:   module,
+		fallback: importer.ForCompiler(fset, runtime.Compiler, nil),
+		packages: map[string]*types.Package{},
+	}
+}
+
+func (m *moduleImporter) Import(path string) (*types.Package, error) {
+	if err := m.ctx.Err(); err != nil {
+		return nil, err
+	}
+	if path != m.module.Path && !strings.HasPrefix(path, m.module.Path+"/") {
+		return m.fallback.Import(path)
+	}
+	if pkg, ok := m.packages[path]; ok {
+		if pkg == nil {
+			return nil, fmt.Errorf("import cycle through %s", path)
+		}
+		return pkg, nil
+	}
+	m.packages[path] = nil
+	dir := filepath.Join(m.module.Dir, filepath.FromSlash(strings.TrimPrefix(path, m.module.Path)))
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []*ast.File
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Mode().IsRegular() || !strings.HasSuffix(name, ".go") || isTestFilename(name) {
+			continue
+		}
+		if ok, err := build.Default.MatchFile(dir, name); err != nil || !ok {
+			continue
+		}
+		file, err := parser.ParseFile(m.fset, filepath.Join(dir, name), nil, 0)
+		if err != nil {
+			continue
+		}
+		files = append(files, file)
+	}
+	conf := types.Config{
+		Importer: m,
+		Error:    func(error) {},
+	}
+	// it's best effort, so ignore errors
+	pkg, _ := conf.Check(path, m.fset, files, nil)
+	m.packages[path] = pkg
+	return pkg, nil
+}
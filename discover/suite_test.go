@@ -0,0 +1,127 @@
+package discover
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTestNamesEmbeddedSuite(t *testing.T) {
+	require.Equal(t,
+		[]string{
+			"TestFooSuite",
+			"TestFooSuite/TestBase",
+			"TestFooSuite/TestDeep",
+			"TestFooSuite/TestFoo",
+		},
+		Names(parseTestNamesGolangAST(context.Background(), Spit(`
+package test
+type DeepSuite struct{}
+func (s *DeepSuite) TestDeep() {}
+type BaseSuite struct{ *DeepSuite }
+func (s *BaseSuite) TestBase() {}
+func (s *BaseSuite) TestWithArgs(x int) {}
+func (s *BaseSuite) SetupTest() {}
+type FooSuite struct {
+	BaseSuite
+}
+func (s *FooSuite) TestFoo() {}
+func TestFooSuite(t *testing.T) {
+	suite.Run(t, new(FooSuite))
+}
+`))))
+}
+
+func TestParsePackageTestNamesEmbeddedSuiteFromModule(t *testing.T) {
+	dir := SpitDir(t, map[string]string{
+		"go.mod": "module example.com/mono\n\ngo 1.16\n",
+		"testutil/base.go": `
+package testutil
+type BaseSuite struct{}
+func (s *BaseSuite) TestBase() {}
+func (s *BaseSuite) Helper() {}
+`,
+		"foo/foo_test.go": `
+package foo
+import (
+	"testing"
+
+	"example.com/mono/testutil"
+)
+type FooSuite struct {
+	testutil.BaseSuite
+}
+func (s *FooSuite) TestFoo() {}
+func TestFooSuite(t *testing.T) {
+	suite.Run(t, new(FooSuite))
+}
+`,
+	})
+	tests, err := parsePackageTestNames(context.Background(), []string{filepath.Join(dir, "foo", "foo_test.go")})
+	require.NoError(t, err)
+	require.Equal(t,
+		[]string{"TestFooSuite", "TestFooSuite/TestBase", "TestFooSuite/TestFoo"},
+		Names(tests))
+	require.Equal(t, "example.com/mono/foo", tests[1].Package)
+	require.Equal(t, filepath.Join(dir, "testutil", "base.go"), tests[1].File)
+	require.Equal(t, KindSuiteMethod, tests[1].Kind)
+
+	// Relative test files give relative inherited methods.
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+	tests, err = parsePackageTestNames(context.Background(), []string{filepath.Join("foo", "foo_test.go")})
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join("foo", "foo_test.go"), tests[0].File)
+	require.Equal(t, filepath.Join("testutil", "base.go"), tests[1].File)
+}
+
+func TestParseTestNamesGenericSuite(t *testing.T) {
+	tests, err := parseTestNamesGolangAST(context.Background(), Spit(`
+package test
+type User struct{}
+type Pair[K comparable, V any] struct{}
+type RepoSuite[T any] struct {
+	suite.Suite
+	repo []T
+}
+func (s *RepoSuite[T]) TestCreate() {}
+func (s *RepoSuite[_]) TestDelete() {}
+func (s *Pair[K, V]) TestSwap() {}
+func TestUserRepo(t *testing.T) {
+	suite.Run(t, new(RepoSuite[User]))
+}
+func TestPair(t *testing.T) {
+	s := &Pair[string, int]{}
+	suite.Run(t, s)
+}
+`))
+	require.NoError(t, err)
+	require.Equal(t,
+		[]string{
+			"TestPair",
+			"TestPair/TestSwap",
+			"TestUserRepo",
+			"TestUserRepo/TestCreate",
+			"TestUserRepo/TestDelete",
+		},
+		Names(tests))
+}
+
+func TestTypeResolverImportedPositions(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "a_test.go", "package a\nimport \"strings\"\nvar b strings.Builder\n", 0)
+	require.NoError(t, err)
+	builder := file.Decls[1].(*ast.GenDecl).Specs[0].(*ast.ValueSpec).Type.(*ast.SelectorExpr).Sel
+	named, ok := newTypeResolver(context.Background(), fset, file).resolveType(builder).(*types.Named)
+	require.True(t, ok)
+	require.Equal(t, "builder.go", filepath.Base(fset.Position(named.Obj().Pos()).Filename))
+}
@@ -0,0 +1,322 @@
+package discover
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/golang"
+
+	_ "embed"
+)
+
+//go:embed trun_string_literal.scm
+var queryTRunStringLiteral []byte
+
+type predicateMatcher interface {
+	Match() (bool, error)
+}
+
+type captureNodes map[string]*sitter.Node
+
+func capturesToMap(q *sitter.Query, captures []sitter.QueryCapture) captureNodes {
+	result := captureNodes{}
+	for _, c := range captures {
+		result[q.CaptureNameForId(c.Index)] = c.Node
+	}
+	return result
+}
+
+func newPredicate(input []byte, q *sitter.Query, m *sitter.QueryMatch) predicateMatcher {
+	predicates := q.PredicatesForPattern(uint32(m.PatternIndex))
+	if len(predicates) == 0 {
+		return &alwaysTruePredicate{}
+	}
+	captures := capturesToMap(q, m.Captures)
+	return &predicate{
+		input:    input,
+		q:        q,
+		m:        m,
+		ps:       predicates,
+		captures: captures,
+	}
+}
+
+type alwaysTruePredicate struct{}
+
+func (this *alwaysTruePredicate) Match() (bool, error) { return true, nil }
+
+type predicate struct {
+	input    []byte
+	q        *sitter.Query
+	m        *sitter.QueryMatch
+	ps       []sitter.QueryPredicateStep
+	captures map[string]*sitter.Node
+}
+
+func (this *predicate) String() string {
+	var b strings.Builder
+	for _, p := range this.ps {
+		if b.Len() > 0 {
+			b.WriteString(" ")
+		}
+		switch p.Type {
+		case sitter.QueryPredicateStepTypeDone:
+			b.WriteString("EOF")
+		case sitter.QueryPredicateStepTypeString:
+			b.WriteString(this.q.StringValueForId(p.ValueId))
+		case sitter.QueryPredicateStepTypeCapture:
+			name := this.q.CaptureNameForId(p.ValueId)
+			value, _ := this.capture(name)
+			b.WriteString(name)
+			b.WriteString("(")
+			b.WriteString(value)
+			b.WriteString(")")
+		default:
+			fmt.Fprintf(&b, "invalid(%v)", p.Type)
+		}
+	}
+	return b.String()
+}
+
+// capture returns the content of the named capture. It returns false when
+// the capture is not part of the match, e.g. an optional one, which the
+// predicates treat as a non-match.
+func (this *predicate) capture(name string) (string, bool) {
+	node := this.captures[name]
+	if node == nil {
+		return "", false
+	}
+	return node.Content(this.input), true
+}
+
+func (this *predicate) Match() (bool, error) {
+	//fmt.Printf("num of predicates: %d: %s\n", len(this.ps), this)
+	ps := this.ps[:]
+	for len(ps) > 0 {
+		name, err := predicateString(this.q, ps[0])
+		if err != nil {
+			return false, err
+		}
+		switch name {
+		case "match?":
+			if len(ps) < 4 {
+				return false, fmt.Errorf("invalid predicate: %s", this)
+			}
+			capture, err := predicateCapture(this.q, ps[1])
+			if err != nil {
+				return false, err
+			}
+			pattern, err := predicateString(this.q, ps[2])
+			if err != nil {
+				return false, err
+			}
+			if err := predicateDone(this.q, ps[3]); err != nil {
+				return false, err
+			}
+			value, ok := this.capture(capture)
+			if !ok {
+				return false, nil
+			}
+			ok, err = regexp.MatchString(pattern, value)
+			if err != nil {
+				return false, fmt.Errorf("invalid #match? pattern: %v", err)
+			}
+			//fmt.Printf("match: @%v='%s' ~= '%s': %v\n", capture, value, pattern, ok)
+			if !ok {
+				return false, nil
+			}
+			ps = ps[4:]
+		case "eq?":
+			if len(ps) < 4 {
+				return false, fmt.Errorf("invalid predicate: %s", this)
+			}
+			capture1, err := predicateCapture(this.q, ps[1])
+			if err != nil {
+				return false, err
+			}
+			capture2, err := predicateCapture(this.q, ps[2])
+			if err != nil {
+				return false, err
+			}
+			if err := predicateDone(this.q, ps[3]); err != nil {
+				return false, err
+			}
+			value1, ok1 := this.capture(capture1)
+			value2, ok2 := this.capture(capture2)
+			if !ok1 || !ok2 || value1 != value2 {
+				return false, nil
+			}
+			ps = ps[4:]
+		default:
+			return false, fmt.Errorf("unsupported predicate: #%s", name)
+		}
+	}
+	return true, nil
+}
+
+func predicateString(q *sitter.Query, p sitter.QueryPredicateStep) (string, error) {
+	switch p.Type {
+	case sitter.QueryPredicateStepTypeString:
+		return q.StringValueForId(p.ValueId), nil
+	}
+	return "", fmt.Errorf("invalid predicate step: want string, got %v", p.Type)
+}
+
+func predicateCapture(q *sitter.Query, p sitter.QueryPredicateStep) (string, error) {
+	switch p.Type {
+	case sitter.QueryPredicateStepTypeCapture:
+		return q.CaptureNameForId(p.ValueId), nil
+	}
+	return "", fmt.Errorf("invalid predicate step: want capture, got %v", p.Type)
+}
+
+func predicateDone(q *sitter.Query, p sitter.QueryPredicateStep) error {
+	switch p.Type {
+	case sitter.QueryPredicateStepTypeDone:
+		return nil
+	}
+	return fmt.Errorf("invalid predicate step: want end, got %v", p.Type)
+}
+
+// scanQuery runs the query and calls cb for every match that satisfies the
+// query predicates. It stops at the first predicate that cannot be
+// evaluated and once ctx is done.
+func scanQuery(ctx context.Context, input []byte, query []byte, root *sitter.Node, cb func(m *sitter.QueryMatch, captures captureNodes)) error {
+	q, err := sitter.NewQuery(query, golang.GetLanguage())
+	if err != nil {
+		return err
+	}
+	defer q.Close()
+	qc := sitter.NewQueryCursor()
+	defer qc.Close()
+	qc.Exec(q, root)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		m, ok := qc.NextMatch()
+		if !ok {
+			break
+		}
+		ok, err := newPredicate(input, q, m).Match()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		// for _, c := range m.Captures {
+		// 	fmt.Printf("- %s = %s\n", q.CaptureNameForId(c.Index), funcName(input, c.Node))
+		// }
+		// fmt.Println("")
+		cb(m, capturesToMap(q, m.Captures))
+	}
+	return nil
+}
+
+// newTreeSitterTest builds a test from the captures of a match. The test is
+// located at @test.location (the t.Run call or the table element) when the
+// query captures it, otherwise at @test.name. It returns false when the
+// match misses @func.name or @test.name.
+func newTreeSitterTest(c captureNodes, input []byte, kind Kind) (Test, bool) {
+	funcName, name := c["func.name"], c["test.name"]
+	if funcName == nil || name == nil {
+		return Test{}, false
+	}
+	parent := funcName.Content(input)
+	location, ok := c["test.location"]
+	if !ok || location == nil {
+		location = name
+	}
+	point := location.StartPoint()
+	return Test{
+		Name:       fmt.Sprintf("%s/%s", parent, subtestName(name.Content(input))),
+		Parent:     parent,
+		Line:       int(point.Row) + 1,
+		Column:     int(point.Column) + 1,
+		Type:       TypeOf(parent),
+		Kind:       kind,
+		Discoverer: DiscovererTreeSitter,
+	}, true
+}
+
+func scanTRunStringLiteral(ctx context.Context, input []byte, root *sitter.Node) ([]Test, error) {
+	query := queryTRunStringLiteral
+	tests := []Test{}
+	err := scanQuery(ctx, input, query, root, func(m *sitter.QueryMatch, c captureNodes) {
+		if test, ok := newTreeSitterTest(c, input, KindSubtest); ok {
+			tests = append(tests, test)
+		}
+	})
+	return tests, err
+}
+
+// scanTreeSitter runs the built-in and the user queries on the file. A
+// query that fails does not stop the others, the tests they find are
+// returned together with the errors. Once ctx is done, the tests found so
+// far are returned with its error.
+func (cfg *config) scanTreeSitter(ctx context.Context, filename string) ([]Test, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	input, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	parser := sitter.NewParser()
+	parser.SetLanguage(golang.GetLanguage())
+	tree := parser.Parse(nil, input)
+	root := tree.RootNode()
+
+	var diags Diagnostics
+	result := []Test{}
+	collect := func(name string, tests []Test, err error) {
+		result = append(result, tests...)
+		if err != nil && err != ctx.Err() {
+			diags.Add(fmt.Errorf("%s: %s: %v", filename, name, err))
+		}
+	}
+	tests, err := scanTRunStringLiteral(ctx, input, root)
+	collect("t.Run query", tests, err)
+	for _, query := range cfg.queries {
+		tests, err := scanUserQuery(ctx, input, query.Source, root)
+		collect(query.Filename, tests, err)
+	}
+	uniqueSubtestNames(result)
+	tests, err = scanGinkgo(ctx, input, root)
+	collect("Ginkgo query", tests, err)
+	for i := range result {
+		result[i].File = filename
+	}
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+	return result, diags.Err()
+}
+
+// var query = flag.String("query", "", "filename with a query")
+// var source = flag.String("source", "", "filename with a source")
+//
+// func main() {
+// 	flag.Parse()
+// 	for _, name := range ScanTreeSitter(*source) {
+// 		fmt.Printf("%s\n", name)
+// 	}
+// }
+//
+// func funcName(content []byte, n *sitter.Node) string {
+// 	if n == nil {
+// 		return ""
+// 	}
+// 	return n.Content(content)
+//
+// 	if n.Type() != "function_declaration" {
+// 		return ""
+// 	}
+//
+// 	return n.ChildByFieldName("name").Content(content)
+// }
@@ -0,0 +1,152 @@
+package discover
+
+import (
+	"context"
+	"testing"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/golang"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTreeSitterTRunStringLiteral(t *testing.T) {
+	require.Equal(t,
+		[]string{"TestWeb", "TestWeb/works"},
+		Names(parseTestNames(context.Background(), Spit(`
+package test
+func TestWeb(t *testing.T) {
+	t.Run("works", func(t *testing.T) {
+	})
+}
+`))))
+}
+
+func TestParseTestNamesTableWithoutRun(t *testing.T) {
+	require.Equal(t,
+		[]string{"TestGroup", "TestGroup/group", "TestGroup/group/a", "TestNoRun"},
+		Names(parseTestNames(context.Background(), Spit(`
+package test
+func TestNoRun(t *testing.T) {
+	tests := []struct{ name string }{{name: "not a subtest"}}
+	_ = tests
+}
+func TestGroup(t *testing.T) {
+	t.Run("group", func(t *testing.T) {
+		tests := []struct{ name string }{{name: "a"}}
+		for _, tc := range tests {
+			t.Run(tc.name, func(t *testing.T) {})
+		}
+	})
+}
+`))))
+}
+
+func TestTreeSitterTRunStructLiteral(t *testing.T) {
+	require.Equal(t,
+		[]string{"TestWeb", "TestWeb/device_event"},
+		Names(parseTestNames(context.Background(), Spit(`
+package test
+func TestWeb(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		data         []byte
+		expectedType EventType
+	}{
+		{
+			name:         "device event",
+			path:         "/api/timon/v1/onDeviceEvent",
+			data:         MakeDeviceEventStartCall(t, "hillary"),
+			expectedType: DeviceEvent,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+		})
+	}
+}
+`))))
+}
+
+func TestTreeSitterLocations(t *testing.T) {
+	tests, err := parseTestNames(context.Background(), Spit(`
+package test
+func TestWeb(t *testing.T) {
+	tests := []struct {
+		name string
+	}{
+		{name: "first"},
+		{
+			name: "second",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {})
+	}
+	t.Run("works", func(t *testing.T) {
+	})
+}
+`))
+	require.NoError(t, err)
+	locations := map[string][2]int{}
+	for _, test := range tests {
+		locations[test.Name] = [2]int{test.Line, test.Column}
+	}
+	require.Equal(t,
+		map[string][2]int{
+			"TestWeb":        {3, 1},
+			"TestWeb/works":  {15, 2},
+			"TestWeb/first":  {7, 3},
+			"TestWeb/second": {8, 3},
+		},
+		locations)
+}
+
+func TestTreeSitterSubtestNames(t *testing.T) {
+	require.Equal(t,
+		[]string{"TestWeb", "TestWeb/a_b", "TestWeb/a_b#01", "TestWeb/raw\\t_string", `TestWeb/say_"hi"`},
+		Names(parseTestNames(context.Background(), Spit(`
+package test
+func TestWeb(t *testing.T) {
+	t.Run("a b", func(t *testing.T) {})
+	t.Run("a\tb", func(t *testing.T) {})
+	t.Run(`+"`raw\\t string`"+`, func(t *testing.T) {})
+	t.Run("say \"hi\"", func(t *testing.T) {})
+}
+`))))
+}
+
+func TestTreeSitterRunReceiverFromParam(t *testing.T) {
+	require.Equal(t,
+		[]string{"TestWeb/works"},
+		Names(parseTestNamesTreeSitter(context.Background(), Spit(`
+package test
+func TestWeb(tt *testing.T) {
+	tt.Run("works", func(t *testing.T) {})
+	t.Run("not the test param", func(t *testing.T) {})
+}
+`))))
+}
+
+func TestScanPredicateOptionalCapture(t *testing.T) {
+	input := []byte("package p\nvar _ = f(\"x\")\nvar _ = f(f, \"y\")\nvar _ = f(g, \"z\")\n")
+	parser := sitter.NewParser()
+	parser.SetLanguage(golang.GetLanguage())
+	root := parser.Parse(nil, input).RootNode()
+	scan := func(predicate string) []string {
+		var result []string
+		err := scanQuery(context.Background(), input, []byte(`
+(call_expression
+  function: (identifier) @fn
+  arguments: (argument_list (identifier)? @opt (interpreted_string_literal) @str)
+  `+predicate+`)
+`), root, func(m *sitter.QueryMatch, c captureNodes) {
+			result = append(result, c["str"].Content(input))
+		})
+		require.NoError(t, err)
+		return result
+	}
+	require.Equal(t, []string{`"y"`}, scan(`(#eq? @opt @fn)`))
+	require.Equal(t, []string{`"z"`}, scan(`(#match? @opt "^g$")`))
+}
@@ -0,0 +1,17 @@
+(function_declaration
+  name: (identifier) @func.name
+  (#match? @func.name "^(Test|Benchmark).*$")
+  parameters: (parameter_list
+    (parameter_declaration
+      name: (identifier) @param.name))
+  body: (block
+    (call_expression
+      function: (selector_expression
+        operand: (identifier) @call.receiver
+        field: (field_identifier) @call.method)
+      (#eq? @call.receiver @param.name)
+      (#match? @call.method "^Run$")
+      arguments: (argument_list
+        [(interpreted_string_literal) (raw_string_literal)] @test.name
+        (func_literal))) @test.location)
+  )
@@ -1,354 +1,155 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
-	"go/ast"
-	"go/importer"
-	"go/parser"
-	"go/token"
-	"go/types"
 	"os"
-	"path/filepath"
-	"sort"
+	"runtime"
 	"strings"
-	"sync"
 	"time"
+
+	"golisttests/discover"
 )
 
 var rootPath = flag.String("root", ".", "root path to start scan for test files (*_test.go)")
 var limitExecution = flag.Bool("limit", false, "enable execution limiter")
 var maxFiles = flag.Int("maxFiles", 10000, "max number of files to scan")
 var maxExecution = flag.Duration("maxExecution", time.Second, "max time limit for scan")
-
-var skipIdents = map[string]bool{
-	"new": true,
-}
-
-func IsTestFilename(name string) bool {
-	return strings.HasSuffix(name, "_test.go")
-}
-
-func IsSingleArgumentTestingT(fn *ast.FuncDecl) bool {
-	if len(fn.Type.Params.List) != 1 {
-		return false
-	}
-	for _, param := range fn.Type.Params.List {
-		if star, ok := param.Type.(*ast.StarExpr); ok {
-			return "&{testing T}" == fmt.Sprintf("%s", star.X)
-		}
-		break
-	}
-	return false
-
-}
-
-func GetReceiverTypeNoStar(fn *ast.FuncDecl) string {
-	switch t := fn.Recv.List[0].Type.(type) {
-	case *ast.StarExpr:
-		return fmt.Sprintf("%s", t.X)
-	case *ast.Ident:
-		return t.Name
+var jobs = flag.Int("j", runtime.NumCPU(), "number of parallel parser workers")
+var cacheMode = flag.String("cache", "readwrite", "cache of discovery results: off, read or readwrite")
+var queryFiles stringList
+var excludes stringList
+var includes stringList
+var runLikeSpecs stringList
+var format = flag.String("format", "text", "output format: text, json or jsonl")
+var stream = flag.Bool("stream", false, "print each test as soon as it is found instead of sorted at the end; text and jsonl formats only")
+var kinds = flag.String("kinds", "test,bench,fuzz,example,ginkgo,gocheck", "comma-separated list of test types to list: test, bench, fuzz, example, ginkgo, gocheck")
+var keepGoing = flag.Bool("keepGoing", true, "list the tests of the other files when some cannot be analysed and report them on stderr")
+var runExpr = flag.String("runExpr", "", "print go test arguments that run the given test instead of listing tests; use - to read lines of text output from stdin")
+
+func init() {
+	flag.Var(&queryFiles, "query", "file with an extra tree-sitter query, may be repeated")
+	flag.Var(&excludes, "exclude", "glob of paths to skip, may be repeated")
+	flag.Var(&includes, "include", "glob of paths to walk even if they are ignored, may be repeated")
+	flag.Var(&runLikeSpecs, "runLike", "extra call starting a subtest as [.]name[:nameArg:bodyArg], a leading dot for a method of the test, may be repeated")
+}
+
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ",") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
+
+// optionsFromFlags returns discovery options for the command line flags.
+func optionsFromFlags() (discover.Options, error) {
+	opts := discover.Options{
+		Root:      *rootPath,
+		Jobs:      *jobs,
+		Excludes:  excludes,
+		Includes:  includes,
+		KeepGoing: *keepGoing,
 	}
-	panic("unknown receiver type")
-}
-
-func HasReceiver(fn *ast.FuncDecl) bool {
-	return fn.Recv != nil
-}
-
-func HasReceiverAndNoArguments(fn *ast.FuncDecl) bool {
-	if len(fn.Type.Params.List) != 0 {
-		return false
-	}
-	return HasReceiver(fn)
-}
-
-func IsTestName(name string) bool {
-	return strings.HasPrefix(name, "Test")
-}
-
-func IsSimpleTest(fn *ast.FuncDecl) bool {
-	return IsTestName(fn.Name.Name) && !HasReceiver(fn) && IsSingleArgumentTestingT(fn)
-}
-
-func IsPossibleSuiteTest(fn *ast.FuncDecl) bool {
-	return IsTestName(fn.Name.Name) && HasReceiverAndNoArguments(fn)
-}
-
-func GetFirstIdent(expr ast.Expr) *ast.Ident {
-	var result *ast.Ident
-	ast.Inspect(expr, func(node ast.Node) bool {
-		if ident, ok := node.(*ast.Ident); ok {
-			if _, ok := skipIdents[ident.Name]; ok {
-				return true
-			}
-			if result == nil {
-				result = ident
-			}
-		}
-		return true
-	})
-	if result == nil {
-		panic("cannot find first identifier")
+	if *limitExecution {
+		opts.MaxFiles = *maxFiles
+		opts.MaxExecution = *maxExecution
 	}
-	return result
-}
-
-func IdentNames(idents []*ast.Ident) []string {
-	result := []string{}
-
-	for _, ident := range idents {
-		result = append(result, ident.Name)
+	queryDir, _ := discover.DefaultQueryDir()
+	queries, err := discover.LoadUserQueries(queryDir, queryFiles)
+	if err != nil {
+		return opts, err
 	}
-
-	return result
-}
-
-func FindSuiteRunTypes(fn *ast.FuncDecl) []*ast.Ident {
-	seen := make(map[string]bool)
-	result := make([]*ast.Ident, 0)
-	ast.Inspect(fn, func(node ast.Node) bool {
-		if call, ok := node.(*ast.CallExpr); ok {
-			if len(call.Args) != 2 {
-				return true
-			}
-			callName := fmt.Sprintf("%s", call.Fun)
-			if callName != "&{suite Run}" {
-				return true
-			}
-			ident := GetFirstIdent(call.Args[1])
-			//fmt.Printf("found call (maybe var), ident name=%v, ident at=%v\n", ident.Name, fset.Position(ident.Pos()))
-			if _, ok := seen[ident.Name]; !ok {
-				result = append(result, ident)
-				seen[ident.Name] = true
-			}
+	opts.Queries = queries
+	for _, spec := range runLikeSpecs {
+		runLike, err := discover.ParseRunLike(spec)
+		if err != nil {
+			return opts, err
 		}
-		return true
-	})
-	return result
-}
-
-func IsSuiteRunner(fn *ast.FuncDecl) bool {
-	return IsSimpleTest(fn) && len(FindSuiteRunTypes(fn)) > 0
-}
-
-type Tracker struct {
-	result                       []string
-	seenTests                    map[string]bool
-	suiteTypesAndTestsWhoRanThem map[string]map[string]bool
-}
-
-func NewTracker() *Tracker {
-	return &Tracker{
-		result:                       make([]string, 0),
-		seenTests:                    make(map[string]bool, 0),
-		suiteTypesAndTestsWhoRanThem: make(map[string]map[string]bool, 0),
+		opts.RunLikes = append(opts.RunLikes, runLike)
 	}
-}
-
-func (t *Tracker) AddTest(name string) {
-	if _, ok := t.seenTests[name]; !ok {
-		t.result = append(t.result, name)
-		t.seenTests[name] = true
-	}
-}
-
-func (t *Tracker) SeenTests() []string {
-	sort.Strings(t.result)
-	return t.result
-}
-
-func (t *Tracker) SuiteRanByTest(suiteTypeName string, testName string) {
-	if _, ok := t.suiteTypesAndTestsWhoRanThem[suiteTypeName]; !ok {
-		t.suiteTypesAndTestsWhoRanThem[suiteTypeName] = make(map[string]bool, 0)
+	if opts.Types, err = discover.ParseTestTypes(*kinds); err != nil {
+		return opts, err
 	}
-	t.suiteTypesAndTestsWhoRanThem[suiteTypeName][testName] = true
-}
-
-func (t *Tracker) WhoRanSuiteType(suiteTypeName string) []string {
-	if _, ok := t.suiteTypesAndTestsWhoRanThem[suiteTypeName]; !ok {
-		return []string{}
+	if opts.CacheMode, err = discover.ParseCacheMode(*cacheMode); err != nil {
+		return opts, err
 	}
-	var result []string
-	for testName, _ := range t.suiteTypesAndTestsWhoRanThem[suiteTypeName] {
-		result = append(result, testName)
+	// The cache can be pruned even when it is off.
+	if opts.CacheDir, err = discover.DefaultCacheDir(); err != nil && opts.CacheMode != discover.CacheOff {
+		return opts, err
 	}
-	return result
+	return opts, nil
 }
 
-type TypeResolver struct {
-	fset *token.FileSet
-	info *types.Info
-}
-
-func NewTypeResolver(fset *token.FileSet, files ...*ast.File) *TypeResolver {
-	conf := types.Config{
-		Importer: importer.Default(),
-		Error:    func(error) {},
-	}
-	info := &types.Info{
-		//Defs: make(map[*ast.Ident]types.Object),
-		Uses: make(map[*ast.Ident]types.Object),
+// runCacheCommand implements the cache subcommands.
+func runCacheCommand(args []string, opts discover.Options) error {
+	if len(args) != 1 || args[0] != "prune" {
+		return fmt.Errorf("usage: golisttests cache prune")
 	}
-	_, err := conf.Check("", fset, files, info)
+	removed, err := discover.PruneCache(opts)
 	if err != nil {
-		// it's best effort, so ignore errors, e.g. import errors
-	}
-
-	return &TypeResolver{
-		fset: fset,
-		info: info,
+		return err
 	}
+	fmt.Printf("removed %d cache entries\n", removed)
+	return nil
 }
 
-func (this *TypeResolver) Resolve(ident *ast.Ident) string {
-	for id, obj := range this.info.Uses {
-		if ident.Name == obj.Name() && ident.Pos() == id.Pos() {
-			typeName := obj.Type().String()
-			switch p := obj.Type().(type) {
-			case *types.Pointer:
-				typeName = p.Elem().String()
-			default:
-			}
-			return typeName
+func main() {
+	flag.Parse()
+	switch *runExpr {
+	case "":
+	case "-":
+		if err := PrintRunArguments(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
+		return
+	default:
+		fmt.Println(RunArguments(discover.Test{Name: *runExpr}))
+		return
 	}
-	return ident.Name // fallback
-	//return ""
-}
-
-func ParseTestNames(filename string) []string {
-	result := []string{}
-	result1 := []string{}
-	result2 := []string{}
-	var wg sync.WaitGroup
-	wg.Add(2)
-	go func() {
-		result1 = ParseTestNamesGolangAST(filename)
-		wg.Done()
-	}()
-	go func() {
-		result2 = ParseTestNamesTreeSitter(filename)
-		wg.Done()
-	}()
-	//result = append(result, ParseTestNamesGolangAST(filename)...)
-	//result = append(result, ParseTestNamesTreeSitter(filename)...)
-	wg.Wait()
-	result = append(result, result1...)
-	result = append(result, result2...)
-	return result
-}
-
-func ParseTestNamesTreeSitter(filename string) []string {
-	return ScanTreeSitter(filename)
-}
-
-func ParseTestNamesGolangAST(filename string) []string {
-	fset := token.NewFileSet()
-	node, err := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+	opts, err := optionsFromFlags()
 	if err != nil {
-		return []string{}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
 	}
-	resolver := NewTypeResolver(fset, node)
-	tracker := NewTracker()
-	scan := func() {
-		for _, f := range node.Decls {
-			if fn, ok := f.(*ast.FuncDecl); ok {
-				testName := fn.Name.Name
-				if IsSimpleTest(fn) {
-					tracker.AddTest(testName)
-					for _, runnableSuiteTypeIdent := range FindSuiteRunTypes(fn) {
-						typeName := resolver.Resolve(runnableSuiteTypeIdent)
-						//fmt.Printf("resolve %v => %v\n", runnableSuiteTypeIdent.Name, typeName)
-						if typeName != "" {
-							tracker.SuiteRanByTest(typeName, testName)
-						}
-					}
-				}
-				if IsPossibleSuiteTest(fn) {
-					receiverTypeName := GetReceiverTypeNoStar(fn)
-					for _, testNameWhoRan := range tracker.WhoRanSuiteType(receiverTypeName) {
-						tracker.AddTest(testNameWhoRan + "/" + testName)
-					}
-				}
-			}
+	if flag.NArg() > 0 && flag.Arg(0) == "cache" {
+		if err := runCacheCommand(flag.Args()[1:], opts); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
-	}
-
-	scan()
-	scan()
-	return tracker.SeenTests()
-}
-
-type Deadliner interface {
-	Tick() error
-}
-
-type Limited struct {
-	expiry   time.Time
-	numFiles int
-}
-
-func (l *Limited) Tick() error {
-	l.numFiles--
-	if l.numFiles <= 0 {
-		return fmt.Errorf("number of files exceeded limit (%d)", *maxFiles)
-	}
-	if time.Now().After(l.expiry) {
-		return fmt.Errorf("execution time exceeded limit (%s)", *maxExecution)
-	}
-	return nil
-}
-
-type Unlimited struct{}
-
-func (u *Unlimited) Tick() error { return nil }
-
-func SlicerSortUniq(input []string) []string {
-	sort.Strings(input)
-	seen := map[string]bool{}
-	var result = make([]string, 0)
-	for _, line := range input {
-		if _, ok := seen[line]; !ok {
-			result = append(result, line)
-			seen[line] = true
+		return
+	}
+	ctx := context.Background()
+	if *stream {
+		linePrinter, ok := linePrinters[*format]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "format cannot be streamed: %s\n", *format)
+			os.Exit(2)
 		}
-	}
-	return result
-}
-
-func ListTestNames(root string, limit Deadliner) ([]string, error) {
-	var result []string
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
+		err = discover.Stream(ctx, opts, func(test discover.Test) error {
+			return linePrinter(os.Stdout, test)
+		})
+	} else {
+		printer, ok := printers[*format]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "unknown format: %s\n", *format)
+			os.Exit(2)
 		}
-		if err := limit.Tick(); err != nil {
-			return err
+		var tests []discover.Test
+		tests, err = discover.Discover(ctx, opts)
+		// A truncated listing is printed anyway, it is what was found in time.
+		if err != nil && !*keepGoing && !discover.IsTruncated(err) {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
-		if !IsTestFilename(path) {
-			return nil
+		if err := printer(os.Stdout, tests); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
-		result = append(result, ParseTestNames(path)...)
-		return nil
-	})
-	return SlicerSortUniq(result), err
-}
-
-func main() {
-	flag.Parse()
-	var names []string
-	var err error
-	if *limitExecution {
-		names, err = ListTestNames(*rootPath, &Limited{time.Now().Add(*maxExecution), *maxFiles})
-	} else {
-		names, err = ListTestNames(*rootPath, &Unlimited{})
-	}
-	for _, name := range names {
-		fmt.Println(name)
 	}
-	if err != nil {
+	if diags, ok := err.(discover.Diagnostics); ok {
+		fmt.Fprintln(os.Stderr, DescribeDiagnostics(diags))
+		os.Exit(1)
+	} else if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"golisttests/discover"
+)
+
+// DescribeDiagnostics summarises the problems of a listing for stderr. A
+// truncated listing is not counted as a problem.
+func DescribeDiagnostics(diags discover.Diagnostics) string {
+	problems := 0
+	for _, err := range diags {
+		if !discover.IsTruncated(err) {
+			problems++
+		}
+	}
+	switch problems {
+	case 0:
+		return fmt.Sprintf("the list is incomplete:\n%v", diags)
+	case 1:
+		return fmt.Sprintf("1 problem, the list may be incomplete:\n%v", diags)
+	}
+	return fmt.Sprintf("%d problems, the list may be incomplete:\n%v", problems, diags)
+}
+
+type Printer func(w io.Writer, tests []discover.Test) error
+
+var printers = map[string]Printer{
+	"text":  PrintText,
+	"json":  PrintJSON,
+	"jsonl": PrintJSONLines,
+}
+
+// A LinePrinter prints a single test, so that tests can be printed as soon
+// as they are found.
+type LinePrinter func(w io.Writer, test discover.Test) error
+
+var linePrinters = map[string]LinePrinter{
+	"text":  PrintTextLine,
+	"jsonl": PrintJSONLine,
+}
+
+func PrintText(w io.Writer, tests []discover.Test) error {
+	for _, test := range tests {
+		if err := PrintTextLine(w, test); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func PrintTextLine(w io.Writer, test discover.Test) error {
+	_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", test.Name, test.Package, test.File, test.Line, test.Type, test.Parent)
+	return err
+}
+
+func PrintJSON(w io.Writer, tests []discover.Test) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(tests)
+}
+
+func PrintJSONLines(w io.Writer, tests []discover.Test) error {
+	for _, test := range tests {
+		if err := PrintJSONLine(w, test); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func PrintJSONLine(w io.Writer, test discover.Test) error {
+	return json.NewEncoder(w).Encode(test)
+}
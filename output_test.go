@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"golisttests/discover"
+)
+
+var outputTests = []discover.Test{
+	{Name: "TestWeb", Package: "example.com/web", File: "web_test.go", Line: 3, Column: 1, Type: discover.TypeTest, Kind: discover.KindSimple, Discoverer: discover.DiscovererGoAST},
+	{Name: "TestWeb/works", Parent: "TestWeb", Package: "example.com/web", File: "web_test.go", Line: 4, Column: 8, Type: discover.TypeTest, Kind: discover.KindSubtest, Discoverer: discover.DiscovererTreeSitter},
+}
+
+func TestPrintText(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, PrintText(&b, outputTests))
+	require.Equal(t, "TestWeb\texample.com/web\tweb_test.go\t3\ttest\t\nTestWeb/works\texample.com/web\tweb_test.go\t4\ttest\tTestWeb\n", b.String())
+}
+
+func TestPrintJSONLines(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, PrintJSONLines(&b, outputTests))
+	require.Equal(t, `{"name":"TestWeb","package":"example.com/web","file":"web_test.go","line":3,"column":1,"type":"test","kind":"simple","discoverer":"go/ast"}
+{"name":"TestWeb/works","parent":"TestWeb","package":"example.com/web","file":"web_test.go","line":4,"column":8,"type":"test","kind":"subtest","discoverer":"tree-sitter"}
+`, b.String())
+}
+
+func TestDescribeDiagnostics(t *testing.T) {
+	truncated := &discover.Truncated{Reason: errors.New("number of files exceeded limit (2)")}
+	require.Equal(t, "the list is incomplete:\ntruncated: number of files exceeded limit (2)",
+		DescribeDiagnostics(discover.Diagnostics{truncated}))
+	require.Equal(t, "1 problem, the list may be incomplete:\na_test.go: bad\ntruncated: number of files exceeded limit (2)",
+		DescribeDiagnostics(discover.Diagnostics{errors.New("a_test.go: bad"), truncated}))
+	require.Equal(t, "2 problems, the list may be incomplete:\na_test.go: bad\nb_test.go: bad",
+		DescribeDiagnostics(discover.Diagnostics{errors.New("a_test.go: bad"), errors.New("b_test.go: bad")}))
+}
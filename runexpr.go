@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"golisttests/discover"
+)
+
+// RunExpression returns an anchored -run pattern that selects exactly the
+// given test. Every level of the name is matched separately, just like
+// go test splits the pattern by slashes.
+func RunExpression(name string) string {
+	levels := strings.Split(name, "/")
+	for i, level := range levels {
+		levels[i] = "^" + regexp.QuoteMeta(level) + "$"
+	}
+	return strings.Join(levels, "/")
+}
+
+// GinkgoFocus returns an anchored -ginkgo.focus pattern for the full text of
+// a container or a spec. It also matches the specs nested in a container.
+func GinkgoFocus(text string) string {
+	return "^" + regexp.QuoteMeta(text) + "( |$)"
+}
+
+// GocheckFilter returns an anchored -check.f pattern that selects exactly
+// the given suite method, named Suite.TestX.
+func GocheckFilter(name string) string {
+	return "^" + regexp.QuoteMeta(name) + "$"
+}
+
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9@%_+=:,./-]+$`)
+
+// ShellQuote quotes s for a POSIX shell using single quotes, unless s is
+// already safe to use as is.
+func ShellQuote(s string) string {
+	if shellSafe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// RunArguments returns go test arguments that run the given test. Benchmarks
+// are selected with -bench and regular tests are skipped for them, Ginkgo
+// specs are selected with -ginkgo.focus and gocheck suite methods with
+// -check.f, narrowed down to their runner when it is known. The type is
+// derived from the name when it is not set. The package is omitted when it
+// is empty.
+func RunArguments(test discover.Test) string {
+	typ := test.Type
+	if typ == "" {
+		typ = discover.TypeOf(test.Name)
+	}
+	var args string
+	switch typ {
+	case discover.TypeBench:
+		args = "-run '^$' -bench " + ShellQuote(RunExpression(test.Name))
+	case discover.TypeGinkgo:
+		args = "-ginkgo.focus " + ShellQuote(GinkgoFocus(test.Name))
+	case discover.TypeGocheck:
+		args = "-check.f " + ShellQuote(GocheckFilter(test.Name))
+		if test.Parent != "" {
+			args = "-run " + ShellQuote(RunExpression(test.Parent)) + " " + args
+		}
+	default:
+		args = "-run " + ShellQuote(RunExpression(test.Name))
+	}
+	if test.Package != "" {
+		args = ShellQuote(test.Package) + " " + args
+	}
+	return args
+}
+
+// PrintRunArguments reads lines in the text output format and prints go test
+// arguments for every one of them.
+func PrintRunArguments(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		test := discover.Test{Name: fields[0]}
+		if len(fields) > 1 {
+			test.Package = fields[1]
+		}
+		if len(fields) > 4 {
+			test.Type = discover.TestType(fields[4])
+		}
+		if len(fields) > 5 {
+			test.Parent = fields[5]
+		}
+		if _, err := fmt.Fprintln(w, RunArguments(test)); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
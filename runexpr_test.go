@@ -0,0 +1,53 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"golisttests/discover"
+)
+
+func TestRunExpression(t *testing.T) {
+	require.Equal(t, `^TestSimple$`, RunExpression("TestSimple"))
+	require.Equal(t, `^TestSampleSuite$/^TestSimple1$`, RunExpression("TestSampleSuite/TestSimple1"))
+	require.Equal(t, `^TestWeb$/^device_event$`, RunExpression("TestWeb/device_event"))
+	require.Equal(t, `^TestWeb$/^f\(x\)_\[1\]\.\*$`, RunExpression("TestWeb/f(x)_[1].*"))
+}
+
+func TestShellQuote(t *testing.T) {
+	require.Equal(t, `'^a$'`, ShellQuote("^a$"))
+	require.Equal(t, `'it'\''s'`, ShellQuote("it's"))
+	require.Equal(t, `example.com/web`, ShellQuote("example.com/web"))
+}
+
+func TestGinkgoFocus(t *testing.T) {
+	require.Equal(t, `^Books when empty( |$)`, GinkgoFocus("Books when empty"))
+	require.Equal(t, `^a\.b \(c\)( |$)`, GinkgoFocus("a.b (c)"))
+}
+
+func TestRunArgumentsGocheck(t *testing.T) {
+	require.Equal(t,
+		`example.com/legacy -run '^Test$' -check.f '^MySuite\.TestHello$'`,
+		RunArguments(discover.Test{Name: "MySuite.TestHello", Parent: "Test", Package: "example.com/legacy", Type: discover.TypeGocheck}))
+	require.Equal(t, `-check.f '^MySuite\.TestHello$'`, RunArguments(discover.Test{Name: "MySuite.TestHello", Type: discover.TypeGocheck}))
+}
+
+func TestPrintRunArguments(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, PrintRunArguments(strings.NewReader(
+		"TestWeb/works\texample.com/web\tweb_test.go\t4\ttest\n"+
+			"TestSimple\n"+
+			"BenchmarkSort/small\n"+
+			"Books when empty (v2)\texample.com/books\tbooks_test.go\t7\tginkgo\tBooks when empty\n"+
+			"MySuite.TestHello\texample.com/legacy\tlegacy_test.go\t12\tgocheck\tTest\n"), &b))
+	require.Equal(t,
+		"example.com/web -run '^TestWeb$/^works$'\n"+
+			"-run '^TestSimple$'\n"+
+			"-run '^$' -bench '^BenchmarkSort$/^small$'\n"+
+			"example.com/books -ginkgo.focus '^Books when empty \\(v2\\)( |$)'\n"+
+			"example.com/legacy -run '^Test$' -check.f '^MySuite\\.TestHello$'\n",
+		b.String())
+}